@@ -1,10 +1,41 @@
 package parser
 
 import (
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/goghcrow/simple-sub/deprecated/token"
 	. "github.com/goghcrow/simple-sub/terms"
 )
 
+// isTag 大写字母开头的标识符为标签 (构造器), e.g. Some, None
+func isTag(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// tupleIndex _1, _2, ... 为 tuple 的投影, 从 1 开始
+func tupleIndex(name string) (int, bool) {
+	if len(name) < 2 || name[0] != '_' || name[1] == '0' {
+		return 0, false
+	}
+	i, err := strconv.Atoi(name[1:])
+	return i, err == nil && i > 0
+}
+
+// destructVar 解构时绑定 rhs 的变量, 不是合法的标识符, 不会与模式中的名字冲突
+const destructVar = "$p"
+
+// sequenceVar 绑定 a; b 中 a 的结果
+const sequenceVar = "$s"
+
+// 字符串插值对应的内置函数, "a ${x} b" => concat (concat "a" (toString x)) " b"
+const (
+	CONCAT    = "concat"
+	TO_STRING = "toString"
+)
+
 type Translate func(expr Term) Term
 
 func Desugar(term Term) Term {
@@ -17,7 +48,12 @@ func Desugar(term Term) Term {
 		return t
 	case *LiteralBool:
 		return t
+	case *LiteralUnit:
+		return t
 	case *Variable:
+		if isTag(t.Name) {
+			return Tg(t.Name, nil)
+		}
 		return t
 	case *Tuple:
 		xs := make([]Term, len(t.Elms))
@@ -40,27 +76,109 @@ func Desugar(term Term) Term {
 	case *Lambda:
 		return Lam(t.Name, Desugar(t.Rhs))
 	case *Application:
+		if v, ok := t.Lhs.(*Variable); ok && isTag(v.Name) {
+			return Tg(v.Name, Desugar(t.Rhs))
+		}
 		return App(Desugar(t.Lhs), Desugar(t.Rhs))
 	case *Selection:
 		return Sel(Desugar(t.Recv), t.FieldName)
+	case *Projection:
+		return Proj(Desugar(t.Recv), t.Index)
+	case *Coalesce:
+		return Coal(Desugar(t.Recv), t.FieldName, Desugar(t.Default))
+	case *Extend:
+		xs := make([]Field, len(t.Fields))
+		for i, fd := range t.Fields {
+			xs[i] = Field{Name: fd.Name, Term: Desugar(fd.Term)}
+		}
+		return Ext(Desugar(t.Recv), xs)
+	case *Restrict:
+		return Rst(Desugar(t.Recv), t.FieldName)
 	case *LetDefine:
 		return Let(t.Name, Desugar(t.Rhs), Desugar(t.Body), t.Rec)
+	case *Tag:
+		if t.Arg == nil {
+			return t
+		}
+		return Tg(t.Name, Desugar(t.Arg))
+	case *Case:
+		xs := make([]Arm, len(t.Arms))
+		for i, arm := range t.Arms {
+			xs[i] = Arm{Tag: arm.Tag, Name: arm.Name, Body: Desugar(arm.Body)}
+		}
+		return Cas(Desugar(t.Scrut), xs)
+	case *NewRef:
+		return NRef(Desugar(t.Init))
+	case *Deref:
+		return Drf(Desugar(t.Ref))
+	case *Assign:
+		return Asgn(Desugar(t.Ref), Desugar(t.Rhs))
+	case *Ascribe:
+		return Asc(Desugar(t.Term), t.Type)
 	case *Unary:
 		return App(Var(t.Name), Desugar(t.Rhs))
 	case *Binary:
 		return App(App(Var(t.Name), Desugar(t.Lhs)), Desugar(t.Rhs))
 	case *Group:
 		return Desugar(t.Term)
+	case *Sequence:
+		// a; b => let $s = (a : unit) in b
+		return Let(sequenceVar, Asc(Desugar(t.Lhs), TyNm("unit")), Desugar(t.Rhs), false)
+	case *Interpolation:
+		var acc Term
+		for _, p := range t.Parts {
+			part := Desugar(p)
+			if _, ok := p.(*LiteralString); !ok {
+				part = App(Var(TO_STRING), part)
+			}
+			if acc == nil {
+				acc = part
+			} else {
+				acc = AppN(Var(CONCAT), acc, part)
+			}
+		}
+		return acc
+	case *Destruct:
+		// let (a, b) = e in body => let $p = e in let a = $p._1 in let b = $p._2 in body
+		// 每个名字单独 let 绑定, 可以各自泛化
+		body := Desugar(t.Body)
+		for i := len(t.Names) - 1; i >= 0; i-- {
+			var rhs Term = Proj(Var(destructVar), i+1)
+			if t.Record {
+				rhs = Sel(Var(destructVar), t.Names[i])
+			}
+			body = Let(t.Names[i], rhs, body, false)
+		}
+		return Let(destructVar, Desugar(t.Rhs), body, false)
 	case *If:
 		return AppN(Var(token.IF), Desugar(t.Cond), Desugar(t.Then), Desugar(t.Else))
 	case *Declaration:
 		return Decl(t.Name, Desugar(t.Rhs), t.Rec)
-	case *Program:
+	case *RecGroup:
 		xs := make([]*Declaration, len(t.Defs))
 		for i, def := range t.Defs {
 			xs[i] = Desugar(def).(*Declaration)
 		}
-		return Pgrm(xs)
+		return RecGrp(xs)
+	case *LetGroup:
+		grp := Desugar(&t.RecGroup).(*RecGroup)
+		return LetGrp(grp.Defs, Desugar(t.Body))
+	case *Program:
+		xs := make([]*Declaration, 0, len(t.Defs))
+		for i := 0; i < len(t.Defs); i++ {
+			def := t.Defs[i]
+			if def.Group == nil {
+				xs = append(xs, Desugar(def).(*Declaration))
+				continue
+			}
+			// 组成员依次相邻, 整组一起处理
+			grp := Desugar(def.Group).(*RecGroup)
+			xs = append(xs, grp.Defs...)
+			i += len(grp.Defs) - 1
+		}
+		pgrm := Pgrm(xs)
+		pgrm.Types = t.Types
+		return pgrm
 	default:
 		panic("unreached")
 		return nil
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/goghcrow/lexer"
 	"github.com/goghcrow/simple-sub/terms"
@@ -22,10 +23,116 @@ func parseFloat(t *lexer.Token) terms.Term {
 	return terms.Float(n)
 }
 
-func parseString(t *lexer.Token) terms.Term {
-	s, err := strconv.Unquote(t.Lexeme)
-	util.Assert(err == nil, "invalid string literal: %s", t.Lexeme)
-	return terms.Str(s)
+// interpDepth 插值表达式中字符串的最大嵌套层数, e.g. "a ${concat "b" "${c}"}" 嵌套两层
+const interpDepth = 2
+
+// strRegex 双引号字符串, 插值表达式中可以出现嵌套 depth 层的字符串与一层花括号
+// 正则不能匹配任意的嵌套, 所以展开为固定的层数, 插值优先于把 $ 作为普通字符
+func strRegex(depth int) string {
+	const esc = `\\["\\trnbf/$]|\\u[0-9a-fA-F]{4}`
+	if depth == 0 {
+		return `"(?:[^"\\]|` + esc + `)*"`
+	}
+	inner := strRegex(depth - 1)
+	interp := `\$\{(?:[^"{}]|\{(?:[^"{}]|` + inner + `)*\}|` + inner + `)*\}`
+	return `"(?:` + interp + `|[^"\\]|` + esc + `)*"`
+}
+
+// parseString 双引号字符串支持插值, e.g. "hello ${name}", 插值表达式由 parseExpr 解析
+// \$ 转义 $, 插值表达式中可以出现字符串, 最多嵌套 interpDepth 层, 参见 strRegex
+// 原始字符串没有转义, 也不支持插值
+func parseString(t *lexer.Token, parseExpr func(string) terms.Term) terms.Term {
+	if t.Lexeme[0] == '`' {
+		s, err := strconv.Unquote(t.Lexeme)
+		util.Assert(err == nil, "invalid string literal: %s", t.Lexeme)
+		return terms.Str(s)
+	}
+	body := t.Lexeme[1 : len(t.Lexeme)-1]
+	var parts []terms.Term
+	var seg strings.Builder
+	flush := func() {
+		s, err := strconv.Unquote(`"` + seg.String() + `"`)
+		util.Assert(err == nil, "invalid string literal: %s", t.Lexeme)
+		if s != "" {
+			parts = append(parts, terms.Str(s))
+		}
+		seg.Reset()
+	}
+	interp := false
+	for i := 0; i < len(body); i++ {
+		switch {
+		case body[i] == '\\':
+			if body[i+1] == '$' {
+				seg.WriteByte('$')
+			} else {
+				seg.WriteString(body[i : i+2])
+			}
+			i++
+		case strings.HasPrefix(body[i:], "${"):
+			end := closingBrace(body, i+2)
+			if end < 0 {
+				panic(&syntaxError{fmt.Sprintf("unclosed interpolation in string literal: %s", t.Lexeme)})
+			}
+			flush()
+			parts = append(parts, parseExpr(body[i+2:end]))
+			interp = true
+			i = end
+		default:
+			seg.WriteByte(body[i])
+		}
+	}
+	flush()
+	if !interp {
+		if len(parts) == 0 {
+			return terms.Str("")
+		}
+		return parts[0]
+	}
+	return terms.Interp(parts...)
+}
+
+// closingBrace 从 start 开始与已经打开的 { 配对的 } 的位置, 跳过其中的字符串, 没有返回 -1
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			if i = closingQuote(s, i+1); i < 0 {
+				return -1
+			}
+		case '`':
+			end := strings.IndexByte(s[i+1:], '`')
+			if end < 0 {
+				return -1
+			}
+			i += end + 1
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// closingQuote 从 start 开始与已经打开的 " 配对的 " 的位置, 跳过转义与插值, 没有返回 -1
+func closingQuote(s string, start int) int {
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"':
+			return i
+		case strings.HasPrefix(s[i:], "${"):
+			if i = closingBrace(s, i+2); i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
 }
 
 func parseInt0(s string) (int64, error) {
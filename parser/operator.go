@@ -0,0 +1,275 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/goghcrow/lexer"
+	"github.com/goghcrow/simple-sub/deprecated/oper"
+	"github.com/goghcrow/simple-sub/terms"
+)
+
+// 操作符
+//
+// 中缀操作符序列先解析为 terms.Operators, 整个程序解析完成后按源码顺序,
+// 以当时已声明的优先级与结合性爬升 (precedence climbing) 结合为 terms.Binary,
+// 前缀操作符组合为 terms.Unary, Desugar 将两者转换为操作符名字的应用, e.g. 1 + 2 * 3 => + 1 (* 2 3)
+//
+// 内置优先级 (由低到高): ?? < || < && < == != < < <= > >= < + - < * / < 前缀操作符 < 函数应用
+// 比较操作符不结合, 连续出现同级的不结合操作符是语法错误, e.g. a < b < c, 1 == 2 == true
+//
+// ?? 不是函数, 左操作数必须是字段选择, r.a ?? d 结合为 terms.Coalesce, 缺少可选字段 a 时取 d
+//
+// 前缀 ! 按操作数的语法区分: 操作数不可能是引用时 (字面量与操作符表达式) 是逻辑非, 组合为 terms.Unary,
+// Desugar 为内置函数 ! 的应用, e.g. !true, !(a && b); 否则是解引用 terms.Deref, e.g. !r, !!r, !(f x)
+// 变量的逻辑非写作前缀 not, e.g. not b, not (a && b)
+// 前缀 - 是 neg, 作用在数字字面量上时直接折叠为负数, e.g. -42
+//
+// 自定义操作符由操作符字符组成, 声明之后的定义中可以作为中缀操作符使用, 优先级即 oper.BP, 可以是小数
+//
+//	infixl 8 +++
+//	infixr 8.5 <|
+//	let (+++) = fun a -> fun b -> ...
+//
+// 连续的操作符字符组成一个操作符, e.g. a+-b 需要写为 a + -b, 自定义操作符不能以 ! 开头, !!r 是两次解引用
+// 前缀操作符只有 - 与 not, 否则 (+++ 1) 既可以是前缀应用也可以是片段
+//
+// 操作符片段 (section), 操作数作为整体, e.g. (* a + b) 即 fun x -> x * (a + b):
+//
+//	(+)    操作符自身
+//	(+ 1)  fun x -> x + 1
+//	(1 +)  fun x -> 1 + x
+//
+// (- 1) 是负数而不是片段
+
+type opInfo struct {
+	oper.BP
+	oper.Fixity
+}
+
+// opTable 中缀操作符的优先级与结合性
+type opTable map[string]opInfo
+
+var builtinOps = opTable{
+	COALESCE:       {oper.BP_COND, oper.INFIX_R},
+	oper.LOGIC_OR:  {oper.BP_LOGIC_OR, oper.INFIX_L},
+	oper.LOGIC_AND: {oper.BP_LOGIC_AND, oper.INFIX_L},
+	oper.EQ:        {oper.BP_EQ, oper.INFIX_N},
+	oper.NE:        {oper.BP_EQ, oper.INFIX_N},
+	oper.LT:        {oper.BP_CMP, oper.INFIX_N},
+	oper.LE:        {oper.BP_CMP, oper.INFIX_N},
+	oper.GT:        {oper.BP_CMP, oper.INFIX_N},
+	oper.GE:        {oper.BP_CMP, oper.INFIX_N},
+	oper.PLUS:      {oper.BP_TERM, oper.INFIX_L},
+	oper.SUB:       {oper.BP_TERM, oper.INFIX_L},
+	oper.MUL:       {oper.BP_FACTOR, oper.INFIX_L},
+	oper.DIV:       {oper.BP_FACTOR, oper.INFIX_L},
+}
+
+func (t opTable) clone() opTable {
+	c := make(opTable, len(t))
+	for name, info := range t {
+		c[name] = info
+	}
+	return c
+}
+
+// NEG 前缀 - 对应的内置函数
+const NEG = "neg"
+
+// COALESCE r.a ?? d
+const COALESCE = "??"
+
+// sectionVar 片段参数的名字, 不是合法的标识符, 不会捕获片段中的变量
+const sectionVar = "$x"
+
+// fixityDecl : infixl 6 +++
+type fixityDecl struct {
+	name string
+	opInfo
+}
+
+// climb operands[i] 与 operands[i+1] 之间是操作符 ops[i]
+func (t opTable) climb(operands []terms.Term, ops []string) terms.Term {
+	i := 0
+	// accept 右操作数中可以出现的操作符
+	var expr func(accept func(oper.BP) bool) terms.Term
+	expr = func(accept func(oper.BP) bool) terms.Term {
+		lhs := operands[i]
+		// 上一个结合的不结合操作符, 同级的操作符不能紧跟其后, e.g. a < b < c
+		var prev string
+		for i < len(ops) {
+			name := ops[i]
+			op, ok := t[name]
+			if !ok {
+				panic(&syntaxError{fmt.Sprintf("undeclared infix operator: %s", name)})
+			}
+			if !accept(op.BP) {
+				break
+			}
+			if prev != "" && t[prev].BP == op.BP {
+				panic(&syntaxError{fmt.Sprintf("non-associative operator %s cannot be chained with %s", prev, name)})
+			}
+			i++
+			// 左结合与不结合的右操作数只能包含优先级更高的操作符, 右结合可以相同
+			bp, fixity := op.BP, op.Fixity
+			rhs := expr(func(x oper.BP) bool { return x > bp || x == bp && fixity == oper.INFIX_R })
+			if fixity == oper.INFIX_N {
+				prev = name
+			} else {
+				prev = ""
+			}
+			if name == COALESCE {
+				lhs = coalesce(lhs, rhs)
+			} else {
+				lhs = terms.Bin(name, fixity, lhs, rhs)
+			}
+		}
+		return lhs
+	}
+	return expr(func(oper.BP) bool { return true })
+}
+
+// resolve 把 term 中所有的 Operators 结合为 Binary, 其余节点原地修改
+func (t opTable) resolve(term terms.Term) terms.Term {
+	switch tm := term.(type) {
+	case *terms.Operators:
+		xs := make([]terms.Term, len(tm.Operands))
+		for i, operand := range tm.Operands {
+			xs[i] = t.resolve(operand)
+		}
+		return t.climb(xs, tm.Ops)
+	case *terms.Tuple:
+		t.resolveAll(tm.Elms)
+	case *terms.Interpolation:
+		t.resolveAll(tm.Parts)
+	case *terms.List:
+		t.resolveAll(tm.Elms)
+	case *terms.Lambda:
+		tm.Rhs = t.resolve(tm.Rhs)
+	case *terms.Application:
+		tm.Lhs, tm.Rhs = t.resolve(tm.Lhs), t.resolve(tm.Rhs)
+	case *terms.Record:
+		t.resolveFields(tm.Fields)
+	case *terms.Selection:
+		tm.Recv = t.resolve(tm.Recv)
+	case *terms.Projection:
+		tm.Recv = t.resolve(tm.Recv)
+	case *terms.Extend:
+		tm.Recv = t.resolve(tm.Recv)
+		t.resolveFields(tm.Fields)
+	case *terms.Restrict:
+		tm.Recv = t.resolve(tm.Recv)
+	case *terms.LetDefine:
+		tm.Rhs, tm.Body = t.resolve(tm.Rhs), t.resolve(tm.Body)
+	case *terms.LetGroup:
+		t.resolve(&tm.RecGroup)
+		tm.Body = t.resolve(tm.Body)
+	case *terms.Declaration:
+		tm.Rhs = t.resolve(tm.Rhs)
+	case *terms.RecGroup:
+		for _, def := range tm.Defs {
+			def.Rhs = t.resolve(def.Rhs)
+		}
+	case *terms.Tag:
+		if tm.Arg != nil {
+			tm.Arg = t.resolve(tm.Arg)
+		}
+	case *terms.Case:
+		tm.Scrut = t.resolve(tm.Scrut)
+		for i := range tm.Arms {
+			tm.Arms[i].Body = t.resolve(tm.Arms[i].Body)
+		}
+	case *terms.NewRef:
+		tm.Init = t.resolve(tm.Init)
+	case *terms.Deref:
+		tm.Ref = t.resolve(tm.Ref)
+	case *terms.Assign:
+		tm.Ref, tm.Rhs = t.resolve(tm.Ref), t.resolve(tm.Rhs)
+	case *terms.Ascribe:
+		tm.Term = t.resolve(tm.Term)
+	case *terms.Unary:
+		tm.Rhs = t.resolve(tm.Rhs)
+	case *terms.Binary:
+		tm.Lhs, tm.Rhs = t.resolve(tm.Lhs), t.resolve(tm.Rhs)
+	case *terms.Group:
+		tm.Term = t.resolve(tm.Term)
+	case *terms.If:
+		tm.Cond, tm.Then, tm.Else = t.resolve(tm.Cond), t.resolve(tm.Then), t.resolve(tm.Else)
+	case *terms.Sequence:
+		tm.Lhs, tm.Rhs = t.resolve(tm.Lhs), t.resolve(tm.Rhs)
+	case *terms.Destruct:
+		tm.Rhs, tm.Body = t.resolve(tm.Rhs), t.resolve(tm.Body)
+	}
+	return term
+}
+
+// coalesce r.a ?? d, 左操作数必须是字段选择
+func coalesce(lhs, rhs terms.Term) terms.Term {
+	for {
+		g, ok := lhs.(*terms.Group)
+		if !ok {
+			break
+		}
+		lhs = g.Term
+	}
+	sel, ok := lhs.(*terms.Selection)
+	if !ok {
+		panic(&syntaxError{fmt.Sprintf("left operand of %s must be a field selection: %s", COALESCE, lhs)})
+	}
+	return terms.Coal(sel.Recv, sel.FieldName, rhs)
+}
+
+func (t opTable) resolveAll(xs []terms.Term) {
+	for i, x := range xs {
+		xs[i] = t.resolve(x)
+	}
+}
+
+func (t opTable) resolveFields(fds []terms.Field) {
+	for i := range fds {
+		fds[i].Term = t.resolve(fds[i].Term)
+	}
+}
+
+// prefix 由内向外组合前缀操作符
+func prefix(toks []*lexer.Token, rhs terms.Term) terms.Term {
+	for i := len(toks) - 1; i >= 0; i-- {
+		switch toks[i].Kind {
+		case SUB:
+			switch lit := rhs.(type) {
+			case *terms.LiteralInt:
+				rhs = terms.Int(-lit.Val)
+			case *terms.LiteralFloat:
+				rhs = terms.Float(-lit.Val)
+			default:
+				rhs = terms.Un(NEG, rhs, true)
+			}
+		case NOT:
+			rhs = terms.Un(toks[i].Lexeme, rhs, true)
+		default:
+			panic("unreached")
+		}
+	}
+	return rhs
+}
+
+// bang 前缀 !, 参见文件开头
+func bang(rhs terms.Term) terms.Term {
+	switch rhs.(type) {
+	case *terms.LiteralBool, *terms.LiteralInt, *terms.LiteralFloat, *terms.LiteralString, *terms.LiteralUnit,
+		*terms.Operators, *terms.Unary:
+		return terms.Un(oper.LOGIC_NOT, rhs, true)
+	default:
+		return terms.Drf(rhs)
+	}
+}
+
+// rightSection (+ e) : fun x -> x + e
+func rightSection(op string, rhs terms.Term) terms.Term {
+	return terms.Lam(sectionVar, terms.Ops([]terms.Term{terms.Var(sectionVar), rhs}, []string{op}))
+}
+
+// leftSection (e +) : fun x -> e + x
+func leftSection(lhs terms.Term, op string) terms.Term {
+	return terms.Lam(sectionVar, terms.Ops([]terms.Term{lhs, terms.Var(sectionVar)}, []string{op}))
+}
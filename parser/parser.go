@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"fmt"
+
 	. "github.com/goghcrow/go-parsec"
 	"github.com/goghcrow/lexer"
+	"github.com/goghcrow/simple-sub/deprecated/oper"
 	"github.com/goghcrow/simple-sub/terms"
 )
 
@@ -10,6 +13,7 @@ import (
 const (
 	LET lexer.TokenKind = iota + 1
 	REC
+	AND
 	IN
 	FUN
 	IF
@@ -18,6 +22,16 @@ const (
 	TRUE
 	FALSE
 	NOT
+	CASE
+	OF
+	REF
+	WITH
+	TYPE
+	CLASS
+	TRAIT
+	INFIXL
+	INFIXR
+	INFIX
 
 	IDENT
 
@@ -27,20 +41,21 @@ const (
 
 	COLON
 	COMMA
+	SEMI_SEMI
+	SEMI
 	ASSIGN
+	REF_ASSIGN
 
 	DOT
+	BACKSLASH
 	ARROW
 
-	//PLUS
-	//PLUSF
-	//SUB
-	//SUBF
-	//MUL
-	//MULF
-	//DIV
-	//DIVF
+	PLUS
+	SUB
+	MUL
+	DIV
 
+	SUBTYPE
 	LE
 	LT
 	GE
@@ -51,6 +66,9 @@ const (
 	LOGIC_OR
 	LOGIC_AND
 	LOGIC_NOT
+	QUESTION
+	UNION
+	OP
 
 	LEFT_PAREN
 	RIGHT_PAREN
@@ -71,8 +89,11 @@ var lex = lexer.BuildLexer(func(l *lexer.Lexicon) {
 	l.Regex(LINE_COMMENT, "//.*").Skip()
 
 	l.Str(NEWLINE, "\n").Skip()
+	l.Str(REF_ASSIGN, ":=")
 	l.Str(COLON, ":")
 	l.Str(COMMA, ",")
+	l.Str(SEMI_SEMI, ";;")
+	l.Str(SEMI, ";")
 
 	l.Str(ASSIGN, "=")
 	l.Str(LEFT_PAREN, "(")
@@ -82,8 +103,11 @@ var lex = lexer.BuildLexer(func(l *lexer.Lexicon) {
 	l.Str(LEFT_BRACE, "{")
 	l.Str(RIGHT_BRACE, "}")
 
+	// 关键字是保留字, 不能作为变量、字段或类型的名字, 参见 TestReservedKeywords
+	// 型变标注 out 不是关键字, 参见 applyTyParams
 	l.Keyword(LET, "let")
 	l.Keyword(REC, "rec")
+	l.Keyword(AND, "and")
 	l.Keyword(IN, "in")
 	l.Keyword(FUN, "fun")
 	l.Keyword(IF, "if")
@@ -92,19 +116,27 @@ var lex = lexer.BuildLexer(func(l *lexer.Lexicon) {
 	l.Keyword(TRUE, "true")
 	l.Keyword(FALSE, "false")
 	l.Keyword(NOT, "not")
+	l.Keyword(CASE, "case")
+	l.Keyword(OF, "of")
+	l.Keyword(REF, "ref")
+	l.Keyword(WITH, "with")
+	l.Keyword(TYPE, "type")
+	l.Keyword(CLASS, "class")
+	l.Keyword(TRAIT, "trait")
+	l.Keyword(INFIXL, "infixl")
+	l.Keyword(INFIXR, "infixr")
+	l.Keyword(INFIX, "infix")
 
 	l.Oper(DOT, ".")
+	l.Oper(BACKSLASH, "\\")
 	l.Oper(ARROW, "->")
 
-	//l.Oper(PLUSF, "+.")
-	//l.Oper(PLUS, "+")
-	//l.Oper(SUBF, "-.")
-	//l.Oper(SUB, "-")
-	//l.Oper(MULF, "*.")
-	//l.Oper(MUL, "*")
-	//l.Oper(DIVF, "/.")
-	//l.Oper(DIV, "/")
+	l.Oper(PLUS, "+")
+	l.Oper(SUB, "-")
+	l.Oper(MUL, "*")
+	l.Oper(DIV, "/")
 
+	l.Oper(SUBTYPE, "<:")
 	l.Oper(LE, "<=")
 	l.Oper(LT, "<")
 	l.Oper(GE, ">=")
@@ -115,15 +147,21 @@ var lex = lexer.BuildLexer(func(l *lexer.Lexicon) {
 	l.Oper(LOGIC_OR, "||")
 	l.Oper(LOGIC_AND, "&&")
 	l.Oper(LOGIC_NOT, "!")
-
-	l.Regex(FLOAT, "[-+]?(?:0|[1-9][0-9]*)(?:[.][0-9]+)+(?:[eE][-+]?[0-9]+)?")
-	l.Regex(FLOAT, "[-+]?(?:0|[1-9][0-9]*)(?:[.][0-9]+)?(?:[eE][-+]?[0-9]+)+")
-	l.Regex(INT, "[-+]?0b(?:0|1[0-1]*)")
-	l.Regex(INT, "[-+]?0x(?:0|[1-9a-fA-F][0-9a-fA-F]*)")
-	l.Regex(INT, "[-+]?0o(?:0|[1-7][0-7]*)")
-	l.Regex(INT, "[-+]?(?:0|[1-9][0-9]*)")
-
-	l.Regex(STR, "\"(?:[^\"\\\\]*|\\\\[\"\\\\trnbf\\/]|\\\\u[0-9a-fA-F]{4})*\"")
+	l.Oper(QUESTION, "?")
+	l.Oper(UNION, "∨")
+	// 自定义操作符, 放在最后, 与上面的操作符等长时优先匹配上面的, 参见 operator.go
+	// 不能以 ! 开头, 否则最长匹配会把 !!r 切分为操作符 !! 与 r
+	l.Regex(OP, "[#$%&*+./<>?@^|~-][!#$%&*+./<=>?@^|~-]*")
+
+	// 数字不带符号, 否则 1-2 会被切分为 1 与 -2, 负数由前缀 - 折叠, 参见 prefix
+	l.Regex(FLOAT, "(?:0|[1-9][0-9]*)(?:[.][0-9]+)+(?:[eE][-+]?[0-9]+)?")
+	l.Regex(FLOAT, "(?:0|[1-9][0-9]*)(?:[.][0-9]+)?(?:[eE][-+]?[0-9]+)+")
+	l.Regex(INT, "0b(?:0|1[0-1]*)")
+	l.Regex(INT, "0x(?:0|[1-9a-fA-F][0-9a-fA-F]*)")
+	l.Regex(INT, "0o(?:0|[1-7][0-7]*)")
+	l.Regex(INT, "(?:0|[1-9][0-9]*)")
+
+	l.Regex(STR, strRegex(interpDepth))
 	l.Regex(STR, "`[^`]*`") // raw string
 
 	l.Regex(IDENT, "[a-zA-Z\\p{L}_][a-zA-Z0-9\\p{L}_]*") // 支持 unicode, 不能以数字开头
@@ -138,6 +176,7 @@ var (
 func init() {
 	var (
 		Term         = NewRule()
+		Expr         = NewRule()
 		Const        = NewRule()
 		Ident        = NewRule()
 		Variable     = NewRule()
@@ -149,47 +188,90 @@ func init() {
 		List         = NewRule()
 		Fun          = NewRule()
 		Let          = NewRule()
+		Bindings     = NewRule()
 		Ite          = NewRule()
+		Case         = NewRule()
+		Ref          = NewRule()
+		Deref        = NewRule()
+		Assign       = NewRule()
 		Apps         = NewRule()
-
-		TopLevel = NewRule()
+		Prefixed     = NewRule()
+		Operators    = NewRule()
+		InfixOp      = NewRule()
+		BindName     = NewRule()
+
+		TypeDecl   = NewRule()
+		ClassDecl  = NewRule()
+		TypeExpr   = NewRule()
+		TyUnion    = NewRule()
+		TyAtom     = NewRule()
+		TyParams   = NewRule()
+		FixityDecl = NewRule()
+		TopLevel   = NewRule()
 	)
 
 	applyTrue := func(v interface{}) interface{} { return terms.Bool(true) }
 	applyFalse := func(v interface{}) interface{} { return terms.Bool(false) }
+	applyTrue2 := func(v interface{}) interface{} { return true }
+	applyFalse2 := func(v interface{}) interface{} { return false }
 	applyInt := func(v interface{}) interface{} { return parseInt(v.(*lexer.Token)) }
 	applyFloat := func(v interface{}) interface{} { return parseFloat(v.(*lexer.Token)) }
-	applyStr := func(v interface{}) interface{} { return parseString(v.(*lexer.Token)) }
+	// 插值表达式与程序一起结合操作符, 参见 applyPgrm
+	parseInterp := func(src string) terms.Term {
+		toks, err := lex.Lex(src)
+		if err == nil {
+			var v interface{}
+			if v, err = ExpectSingleResult(ExpectEOF(Term.Parse(toks))); err == nil {
+				return v.(terms.Term)
+			}
+		}
+		panic(&syntaxError{fmt.Sprintf("invalid interpolation ${%s}: %s", src, err)})
+	}
+	applyStr := func(v interface{}) interface{} { return parseString(v.(*lexer.Token), parseInterp) }
 	applyIdent := func(v interface{}) interface{} { return v.(*lexer.Token).Lexeme }
 	applyVar := func(v interface{}) interface{} { return terms.Var(v.(*lexer.Token).Lexeme) }
+	// r.a.b \ c, 选择与删除字段都是左结合的后缀
 	applySubTerm := func(v interface{}) interface{} {
 		t2 := v.([]interface{})
-		rcd := t2[0].(terms.Term)
-		ids := t2[1].([]interface{})
-		if len(ids) == 0 {
-			return rcd
-		}
-		sub := terms.Sel(rcd, ids[0].(string))
-		for i := 1; i < len(ids); i++ {
-			sub = terms.Sel(sub, ids[i].(string))
+		sub := t2[0].(terms.Term)
+		for _, postfix := range t2[1].([]interface{}) {
+			sub = postfix.(func(terms.Term) terms.Term)(sub)
 		}
 		return sub
 	}
-	applyRecord := func(v interface{}) interface{} {
-		if v == nil {
-			return terms.Rcd([]terms.Field{})
+	// t._1 是 tuple 的投影, 接收者不是 tuple 时由类型检查回退为字段选择, 参见 typer/ts_infer.go
+	applySel := func(v interface{}) interface{} {
+		name := v.(string)
+		if i, ok := tupleIndex(name); ok {
+			return func(recv terms.Term) terms.Term { return terms.Proj(recv, i) }
 		}
+		return func(recv terms.Term) terms.Term { return terms.Sel(recv, name) }
+	}
+	applyRst := func(v interface{}) interface{} {
+		return func(recv terms.Term) terms.Term { return terms.Rst(recv, v.(string)) }
+	}
+	applyFields := func(v interface{}) []terms.Field {
 		pairs := v.([]interface{})
 		xs := make([]terms.Field, len(pairs))
 		for i, it := range pairs {
 			t3 := it.([]interface{})
 			xs[i] = terms.Field{Name: t3[0].(string), Term: t3[2].(terms.Term)}
 		}
-		return terms.Rcd(xs)
+		return xs
+	}
+	applyRecord := func(v interface{}) interface{} {
+		if v == nil {
+			return terms.Rcd([]terms.Field{})
+		}
+		return terms.Rcd(applyFields(v))
+	}
+	applyExtend := func(v interface{}) interface{} {
+		t3 := v.([]interface{})
+		return terms.Ext(t3[0].(terms.Term), applyFields(t3[2]))
 	}
 	applyTuple := func(v interface{}) interface{} {
 		if v == nil {
-			return terms.Tup() // 0
+			return terms.Unit()
 		}
 		a := v.([]interface{})
 		fst := a[0].(terms.Term)
@@ -219,12 +301,38 @@ func init() {
 		t4 := v.([]interface{})
 		return terms.LamN(t4[1].([]string), t4[3].(terms.Term))
 	}
+	applyBinding := func(v interface{}) interface{} {
+		t4 := v.([]interface{})
+		name := t4[1].(string)
+		rhs := t4[3].(terms.Term)
+		return terms.Decl(name, rhs, false)
+	}
+	// let rec f = ... and g = ..., 只有一个定义时为普通的 let rec
+	applyRecBindings := func(v interface{}) interface{} {
+		t5 := v.([]interface{})
+		fst := terms.Decl(t5[1].(string), t5[3].(terms.Term), true)
+		rest := t5[4].([]interface{})
+		if len(rest) == 0 {
+			return fst
+		}
+		defs := make([]*terms.Declaration, 1+len(rest))
+		defs[0] = fst
+		for i, it := range rest {
+			t3 := it.([]interface{})
+			defs[i+1] = terms.Decl(t3[0].(string), t3[2].(terms.Term), true)
+		}
+		return terms.RecGrp(defs)
+	}
 	applyLet := func(v interface{}) interface{} {
-		t6 := v.([]interface{})
-		name := t6[1].(string)
-		rhs := t6[3].(terms.Term)
-		body := t6[5].(terms.Term)
-		return terms.Let(name, rhs, body, t6[0] != nil)
+		t3 := v.([]interface{})
+		body := t3[2].(terms.Term)
+		switch b := t3[0].(type) {
+		case *terms.RecGroup:
+			return terms.LetGrp(b.Defs, body)
+		default:
+			def := b.(*terms.Declaration)
+			return terms.Let(def.Name, def.Rhs, body, def.Rec)
+		}
 	}
 	applyIte := func(v interface{}) interface{} {
 		t6 := v.([]interface{})
@@ -233,6 +341,40 @@ func init() {
 		els := t6[5].(terms.Term)
 		return terms.Iff(cond, then, els)
 	}
+	applyCase := func(v interface{}) interface{} {
+		t6 := v.([]interface{})
+		scrut := t6[1].(terms.Term)
+		xs := t6[4].([]interface{})
+		arms := make([]terms.Arm, len(xs))
+		for i, x := range xs {
+			t4 := x.([]interface{})
+			arm := terms.Arm{Tag: t4[0].(string), Body: t4[3].(terms.Term)}
+			if t4[1] != nil {
+				arm.Name = t4[1].(string)
+			}
+			arms[i] = arm
+		}
+		return terms.Cas(scrut, arms)
+	}
+	// a; b; c, 右结合
+	applySeq := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		if t2[1] == nil {
+			return t2[0]
+		}
+		return terms.Sq(t2[0].(terms.Term), t2[1].(terms.Term))
+	}
+	applyTopExpr := func(v interface{}) interface{} { return terms.TopExpr(v.(terms.Term)) }
+	applyRef := func(v interface{}) interface{} { return terms.NRef(v.(terms.Term)) }
+	applyDeref := func(v interface{}) interface{} { return bang(v.(terms.Term)) }
+	applyAssign := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		lhs := t2[0].(terms.Term)
+		if t2[1] == nil {
+			return lhs
+		}
+		return terms.Asgn(lhs, t2[1].(terms.Term))
+	}
 	applyApps := func(v interface{}) interface{} {
 		t2 := v.([]interface{})
 		lhs := t2[0].(terms.Term)
@@ -247,19 +389,190 @@ func init() {
 		}
 		return app
 	}
-	applyTopLevel := func(v interface{}) interface{} {
+	applyTyFields := func(v interface{}) []terms.TyField {
+		xs := []terms.TyField{}
+		if v == nil {
+			return xs
+		}
+		for _, it := range v.([]interface{}) {
+			t3 := it.([]interface{})
+			xs = append(xs, terms.TyField{Name: t3[0].(string), Type: t3[2].(terms.TypeExpr)})
+		}
+		return xs
+	}
+	// Box 或 Box[int]
+	applyTyName := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		name := t2[0].(string)
+		if t2[1] == nil {
+			return terms.TyNm(name)
+		}
+		var args []terms.TypeExpr
+		for _, it := range t2[1].([]interface{}) {
+			args = append(args, it.(terms.TypeExpr))
+		}
+		return terms.TyAp(name, args)
+	}
+	// 字面量类型, e.g. "GET", 42
+	applyTyLit := func(v interface{}) interface{} { return terms.TyLt(v.(terms.Term)) }
+	// 插值字符串不是字面量
+	applyTyStr := func(v interface{}) interface{} {
+		lit := applyStr(v).(terms.Term)
+		if _, ok := lit.(*terms.Interpolation); ok {
+			panic(&syntaxError{fmt.Sprintf("invalid literal type: %s", v.(*lexer.Token).Lexeme)})
+		}
+		return terms.TyLt(lit)
+	}
+	// a ∨ b ∨ c, 左结合
+	applyTyUnion := func(v interface{}) interface{} {
+		xs := v.([]interface{})
+		res := xs[0].(terms.TypeExpr)
+		for _, it := range xs[1:] {
+			res = terms.TyUn(res, it.(terms.TypeExpr))
+		}
+		return res
+	}
+	// 动态类型 ? 作为名字为 ? 的类型, 参见 typer/ts_dynamic.go
+	applyTyDyn := func(v interface{}) interface{} { return terms.TyNm("?") }
+	// out 不是关键字, 只在型变标注的位置有特殊含义, 其他位置可以作为名字, e.g. let out = 1
+	applyTyParams := func(v interface{}) interface{} {
+		var xs []terms.TyParam
+		for _, it := range v.([]interface{}) {
+			t2, ok := it.([]interface{})
+			if !ok {
+				xs = append(xs, terms.TyParam{Name: it.(string)})
+				continue
+			}
+			p := terms.TyParam{Variance: t2[0].(*lexer.Token).Lexeme, Name: t2[1].(string)}
+			if p.Variance != "in" && p.Variance != "out" {
+				panic(&syntaxError{fmt.Sprintf("invalid variance of type parameter %s: %s", p.Name, p.Variance)})
+			}
+			xs = append(xs, p)
+		}
+		return xs
+	}
+	// 声明的后半部分映射为 名字 -> 声明 的闭包
+	applyPrimSuper := func(v interface{}) interface{} {
+		return func(name string) terms.TypeDecl { return terms.PrimDcl(name, v.(string)) }
+	}
+	applyCtorDecl := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		var body terms.TypeExpr
+		if t2[1] != nil {
+			body = t2[1].(terms.TypeExpr)
+		}
+		return func(name string) terms.TypeDecl { return terms.CtorDcl(name, t2[0].([]terms.TyParam), body) }
+	}
+	applyAlias := func(v interface{}) interface{} {
+		return func(name string) terms.TypeDecl { return terms.CtorDcl(name, nil, v.(terms.TypeExpr)) }
+	}
+	applyTypeDecl := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		name := t2[0].(string)
+		if t2[1] == nil {
+			return terms.PrimDcl(name, "")
+		}
+		return t2[1].(func(string) terms.TypeDecl)(name)
+	}
+	// (e) 或 (e : T)
+	applyParens := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		if t2[1] == nil {
+			return t2[0]
+		}
+		return terms.Asc(t2[0].(terms.Term), t2[1].(terms.TypeExpr))
+	}
+	applyOpVar := func(v interface{}) interface{} { return terms.Var(v.(string)) }
+	applyRightSection := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		return rightSection(t2[0].(string), t2[1].(terms.Term))
+	}
+	applyLeftSection := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		return leftSection(t2[0].(terms.Term), t2[1].(string))
+	}
+	// 记录类型的字段可以是可选的, e.g. {port?: int}
+	applyTyRecord := func(v interface{}) interface{} {
+		xs := []terms.TyField{}
+		if v != nil {
+			for _, it := range v.([]interface{}) {
+				t4 := it.([]interface{})
+				xs = append(xs, terms.TyField{Name: t4[0].(string), Type: t4[3].(terms.TypeExpr), Optional: t4[1] != nil})
+			}
+		}
+		return terms.TyRcd(xs)
+	}
+	// a -> b -> c, 右结合
+	applyTypeExpr := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		lhs := t2[0].(terms.TypeExpr)
+		if t2[1] == nil {
+			return lhs
+		}
+		return terms.TyFn(lhs, t2[1].(terms.TypeExpr))
+	}
+	// 字段的闭包区分 "没有声明字段" 与 "声明了空字段 {}"
+	applyClassFields := func(v interface{}) interface{} {
+		return func() []terms.TyField { return applyTyFields(v) }
+	}
+	applyClassDecl := func(v interface{}) interface{} {
 		t4 := v.([]interface{})
+		trait := t4[0].(bool)
 		name := t4[1].(string)
-		rhs := t4[3].(terms.Term)
-		return terms.Decl(name, rhs, t4[0] != nil)
+		var parents []string
+		if t4[2] != nil {
+			for _, it := range t4[2].([]interface{}) {
+				parents = append(parents, it.(string))
+			}
+		}
+		var fields []terms.TyField
+		if t4[3] != nil {
+			fields = t4[3].(func() []terms.TyField)()
+		}
+		return terms.ClassDcl(trait, name, parents, fields)
 	}
+	applyFixity := func(v interface{}) interface{} {
+		t3 := v.([]interface{})
+		var fixity oper.Fixity
+		switch t3[0].(*lexer.Token).Kind {
+		case INFIXL:
+			fixity = oper.INFIX_L
+		case INFIXR:
+			fixity = oper.INFIX_R
+		default:
+			fixity = oper.INFIX_N
+		}
+		var bp oper.BP
+		switch lit := t3[1].(terms.Term).(type) {
+		case *terms.LiteralInt:
+			bp = oper.BP(lit.Val)
+		case *terms.LiteralFloat:
+			bp = oper.BP(lit.Val)
+		}
+		return &fixityDecl{t3[2].(string), opInfo{bp, fixity}}
+	}
+	// 按源码顺序结合操作符, 优先级声明只影响之后的定义
 	applyPgrm := func(v interface{}) interface{} {
 		xs := v.([]interface{})
-		defs := make([]*terms.Declaration, len(xs))
-		for i, x := range xs {
-			defs[i] = x.(*terms.Declaration)
+		defs := make([]*terms.Declaration, 0, len(xs))
+		var decls []terms.TypeDecl
+		ops := builtinOps.clone()
+		for _, x := range xs {
+			switch def := x.(type) {
+			case *fixityDecl:
+				ops[def.name] = def.opInfo
+			case *terms.Declaration:
+				defs = append(defs, ops.resolve(def).(*terms.Declaration))
+			case *terms.RecGroup:
+				// 组成员在 Program.Defs 中依次相邻
+				defs = append(defs, ops.resolve(def).(*terms.RecGroup).Defs...)
+			case terms.TypeDecl:
+				decls = append(decls, def)
+			}
 		}
-		return terms.Pgrm(defs)
+		pgrm := terms.Pgrm(defs)
+		pgrm.Types = decls
+		return pgrm
 	}
 	applyAtLeastIdent := func(v interface{}) interface{} {
 		t2 := v.([]interface{})
@@ -273,31 +586,53 @@ func init() {
 		return xs
 	}
 
-	//Signed       = NewRule()
-	//Factor       = NewRule()
-	//Addition     = NewRule()
-	//AddSub := Alt(Tok(PLUS), Tok(SUB), Tok(PLUSF), Tok(SUBF))
-	//MulDiv := Alt(Tok(MUL), Tok(MULF), Tok(DIV), Tok(DIVF))
-	//Signed.Pattern = Seq(OptSc(Alt(Tok(PLUS), Tok(SUB))), Term).Map(applyUnary)
-	//Factor.Pattern = LRecSc(Signed, Seq(MulDiv, Signed), applyBinary)
-	//Addition.Pattern = LRecSc(Factor, Seq(AddSub, Factor), applyBinary)
-	//applyUnary := func(v interface{}) interface{} {
-	//	t2 := v.([]interface{})
-	//	oper := t2[0].(*lexer.Token)
-	//	rhs := t2[1].(terms.Term)
-	//	if oper == nil {
-	//		return rhs
-	//	}
-	//	return terms.App(terms.Var(oper.Lexeme), rhs)
-	//}
-	//applyBinary := func(a, b interface{}) interface{} {
-	//	lhs := a.(interface{}).(terms.Term)
-	//	oper := b.([]interface{})[0].(*lexer.Token)
-	//	rhs := b.([]interface{})[1].(terms.Term)
-	//	return terms.AppN(terms.Var(oper.Lexeme), lhs, rhs)
-	//}
-
-	Term.Pattern = Alt(Let, Fun, Ite, Apps)
+	// 模式映射为 (rhs, body) -> 解构 的闭包
+	applyTuplePat := func(v interface{}) interface{} {
+		t3 := v.([]interface{})
+		xs := []string{t3[0].(string)}
+		for _, it := range t3[2].([]interface{}) {
+			xs = append(xs, it.(string))
+		}
+		return func(rhs, body terms.Term) terms.Term { return terms.Dstr(xs, false, rhs, body) }
+	}
+	applyRcdPat := func(v interface{}) interface{} {
+		var xs []string
+		for _, it := range v.([]interface{}) {
+			xs = append(xs, it.(string))
+		}
+		return func(rhs, body terms.Term) terms.Term { return terms.Dstr(xs, true, rhs, body) }
+	}
+	applyDestruct := func(v interface{}) interface{} {
+		t6 := v.([]interface{})
+		return t6[1].(func(rhs, body terms.Term) terms.Term)(t6[3].(terms.Term), t6[5].(terms.Term))
+	}
+
+	applyPrefixed := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		var toks []*lexer.Token
+		for _, it := range t2[0].([]interface{}) {
+			toks = append(toks, it.(*lexer.Token))
+		}
+		return prefix(toks, t2[1].(terms.Term))
+	}
+	applyOperators := func(v interface{}) interface{} {
+		t2 := v.([]interface{})
+		operands := []terms.Term{t2[0].(terms.Term)}
+		var ops []string
+		for _, it := range t2[1].([]interface{}) {
+			t2 := it.([]interface{})
+			ops = append(ops, t2[0].(string))
+			operands = append(operands, t2[1].(terms.Term))
+		}
+		if len(ops) == 0 {
+			return operands[0]
+		}
+		return terms.Ops(operands, ops)
+	}
+
+	// ; 的优先级最低, let/fun/if 的 body 尽量向右延伸, e.g. let x = 1 in a; b 即 let x = 1 in (a; b)
+	Term.Pattern = Seq(Expr, OptSc(KRight(Tok(SEMI), Term))).Map(applySeq)
+	Expr.Pattern = Alt(Let, Fun, Ite, Case, Assign)
 	Const.Pattern = Alt(
 		Tok(INT).Map(applyInt),
 		Tok(FLOAT).Map(applyFloat),
@@ -307,14 +642,42 @@ func init() {
 	)
 	Ident.Pattern = Alt(Tok(IDENT), Tok(TRUE), Tok(FALSE)).Map(applyIdent)
 	Variable.Pattern = Tok(IDENT).Map(applyVar)
-	Parens.Pattern = KMid(Tok(LEFT_PAREN), Term, Tok(RIGHT_PAREN))
-	SubTermNoSel.Pattern = Alt(Parens, Record, Tuple, List, Const, Variable)
-	SubTerm.Pattern = Seq(SubTermNoSel, RepSc(KRight(Tok(DOT), Ident))).Map(applySubTerm)
+	// (- e) 是负数, 不是片段
+	sectionOp := Alt(
+		Tok(LOGIC_OR), Tok(LOGIC_AND),
+		Tok(EQ), Tok(NE), Tok(LT), Tok(LE), Tok(GT), Tok(GE),
+		Tok(PLUS), Tok(MUL), Tok(DIV),
+		Tok(OP),
+	).Map(applyIdent)
+	Parens.Pattern = KMid(
+		Tok(LEFT_PAREN),
+		Alt(
+			Seq(Term, OptSc(KRight(Tok(COLON), TypeExpr))).Map(applyParens),
+			// 操作符片段, 参见 operator.go
+			InfixOp.Map(applyOpVar),
+			Seq(sectionOp, Operators).Map(applyRightSection),
+			Seq(Operators, InfixOp).Map(applyLeftSection),
+		),
+		Tok(RIGHT_PAREN),
+	)
+	SubTermNoSel.Pattern = Alt(Parens, Record, Tuple, List, Const, Variable, Ref, Deref)
+	SubTerm.Pattern = Seq(
+		SubTermNoSel,
+		RepSc(Alt(
+			KRight(Tok(DOT), Ident).Map(applySel),
+			KRight(Tok(BACKSLASH), Ident).Map(applyRst),
+		)),
+	).Map(applySubTerm)
+	fields := ListSc(Seq(Ident, Tok(COLON), Term), Tok(COMMA))
+	// { r with a: 1 } 扩展或覆盖记录 r 的字段
 	Record.Pattern = KMid(
 		Tok(LEFT_BRACE),
-		OptSc(ListSc(Seq(Ident, Tok(COLON), Term), Tok(COMMA))),
+		Alt(
+			OptSc(fields).Map(applyRecord),
+			Seq(Term, Tok(WITH), fields).Map(applyExtend),
+		),
 		Tok(RIGHT_BRACE),
-	).Map(applyRecord)
+	)
 	Tuple.Pattern = KMid(
 		Tok(LEFT_PAREN),
 		Alt(Nil(), Seq(Term, Tok(COMMA), OptSc(ListSc(Term, Tok(COMMA))))),
@@ -327,14 +690,106 @@ func init() {
 	).Map(applyList)
 	atLeastIdent := Seq(Ident, RepSc(Ident)).Map(applyAtLeastIdent)
 	Fun.Pattern = Seq(Tok(FUN), atLeastIdent, Tok(ARROW), Term).Map(applyFun)
-	Let.Pattern = Seq(KRight(Tok(LET), OptSc(Tok(REC))), Ident, Tok(ASSIGN), Term, Tok(IN), Term).Map(applyLet)
+	// let (+++) = ... 定义操作符
+	BindName.Pattern = Alt(Ident, KMid(Tok(LEFT_PAREN), InfixOp, Tok(RIGHT_PAREN)))
+	Bindings.Pattern = Alt(
+		Seq(Tok(LET), BindName, Tok(ASSIGN), Term).Map(applyBinding),
+		Seq(
+			KRight(Tok(LET), Tok(REC)), BindName, Tok(ASSIGN), Term,
+			RepSc(KRight(Tok(AND), Seq(BindName, Tok(ASSIGN), Term))),
+		).Map(applyRecBindings),
+	)
+	// let (a, b) = e in ..., let {x, y} = r in ...
+	pattern := Alt(
+		KMid(Tok(LEFT_PAREN), Seq(Ident, Tok(COMMA), ListSc(Ident, Tok(COMMA))), Tok(RIGHT_PAREN)).Map(applyTuplePat),
+		KMid(Tok(LEFT_BRACE), ListSc(Ident, Tok(COMMA)), Tok(RIGHT_BRACE)).Map(applyRcdPat),
+	)
+	Let.Pattern = Alt(
+		Seq(Bindings, Tok(IN), Term).Map(applyLet),
+		Seq(Tok(LET), pattern, Tok(ASSIGN), Term, Tok(IN), Term).Map(applyDestruct),
+	)
 	Ite.Pattern = Seq(Tok(IF), Term, Tok(THEN), Term, Tok(ELSE), Term).Map(applyIte)
+	Ref.Pattern = KRight(Tok(REF), SubTerm).Map(applyRef)
+	Deref.Pattern = KRight(Tok(LOGIC_NOT), SubTerm).Map(applyDeref)
+	// r := e, 右结合, 赋值的目标可以是任意 Operators, e.g. obj.cell := 1
+	Assign.Pattern = Seq(Operators, OptSc(KRight(Tok(REF_ASSIGN), Expr))).Map(applyAssign)
+	// 标签以大写字母开头, e.g. case opt of { Some x -> x, None -> 0 }
+	Case.Pattern = Seq(
+		Tok(CASE), Term, Tok(OF),
+		Tok(LEFT_BRACE),
+		ListSc(Seq(Ident, OptSc(Ident), Tok(ARROW), Term), Tok(COMMA)),
+		Tok(RIGHT_BRACE),
+	).Map(applyCase)
 	// 变量和函数都使用 let 声明, 变量是零参函数, 函数是有参变量, apply 的语法就统一了
 	Apps.Pattern = Seq(SubTerm, RepSc(SubTerm)).Map(applyApps)
-
-	_Expr.Pattern = Term
-
-	TopLevel.Pattern = Seq(KRight(Tok(LET), OptSc(Tok(REC))), Ident, Tok(ASSIGN), Term).Map(applyTopLevel)
+	// 参见 operator.go
+	Prefixed.Pattern = Seq(RepSc(Alt(Tok(SUB), Tok(NOT))), Apps).Map(applyPrefixed)
+	Operators.Pattern = Seq(Prefixed, RepSc(Seq(InfixOp, Prefixed))).Map(applyOperators)
+	InfixOp.Pattern = Alt(
+		Tok(LOGIC_OR), Tok(LOGIC_AND),
+		Tok(EQ), Tok(NE), Tok(LT), Tok(LE), Tok(GT), Tok(GE),
+		Tok(PLUS), Tok(SUB), Tok(MUL), Tok(DIV),
+		Tok(OP),
+	).Map(applyIdent)
+
+	_Expr.Pattern = Term.Map(func(v interface{}) interface{} { return builtinOps.resolve(v.(terms.Term)) })
+
+	// type nat <: int
+	// type Box[A] = { value: A }, type List[out A]
+	// type Point = { x: int, y: int }
+	TyParams.Pattern = KMid(
+		Tok(LEFT_BRACKET),
+		ListSc(Alt(Seq(Alt(Tok(IN), Tok(IDENT)), Ident), Ident), Tok(COMMA)),
+		Tok(RIGHT_BRACKET),
+	).Map(applyTyParams)
+	TypeDecl.Pattern = KRight(
+		Tok(TYPE),
+		Seq(Ident, OptSc(Alt(
+			KRight(Tok(SUBTYPE), Ident).Map(applyPrimSuper),
+			Seq(TyParams, OptSc(KRight(Tok(ASSIGN), TypeExpr))).Map(applyCtorDecl),
+			KRight(Tok(ASSIGN), TypeExpr).Map(applyAlias),
+		))),
+	).Map(applyTypeDecl)
+	// int, {x: int}, (int -> int) -> int, ? 是动态类型
+	TyAtom.Pattern = Alt(
+		Tok(QUESTION).Map(applyTyDyn),
+		Alt(Tok(INT).Map(applyInt), Tok(FLOAT).Map(applyFloat)).Map(applyTyLit),
+		Tok(STR).Map(applyTyStr),
+		Seq(Ident, OptSc(KMid(Tok(LEFT_BRACKET), ListSc(TypeExpr, Tok(COMMA)), Tok(RIGHT_BRACKET)))).Map(applyTyName),
+		KMid(
+			Tok(LEFT_BRACE),
+			OptSc(ListSc(Seq(Ident, OptSc(Tok(QUESTION)), Tok(COLON), TypeExpr), Tok(COMMA))),
+			Tok(RIGHT_BRACE),
+		).Map(applyTyRecord),
+		KMid(Tok(LEFT_PAREN), TypeExpr, Tok(RIGHT_PAREN)),
+	)
+	// ∨ 比 -> 结合得紧, e.g. int ∨ bool -> int 即 (int ∨ bool) -> int
+	TyUnion.Pattern = ListSc(TyAtom, Tok(UNION)).Map(applyTyUnion)
+	TypeExpr.Pattern = Seq(TyUnion, OptSc(KRight(Tok(ARROW), TypeExpr))).Map(applyTypeExpr)
+	// class Point: Base, Showable { x: int, y: int }
+	// trait Showable
+	ClassDecl.Pattern = Seq(
+		Alt(Tok(CLASS).Map(applyFalse2), Tok(TRAIT).Map(applyTrue2)),
+		Ident,
+		OptSc(KRight(Tok(COLON), ListSc(Ident, Tok(COMMA)))),
+		OptSc(KMid(
+			Tok(LEFT_BRACE),
+			OptSc(ListSc(Seq(Ident, Tok(COLON), TypeExpr), Tok(COMMA))),
+			Tok(RIGHT_BRACE),
+		).Map(applyClassFields)),
+	).Map(applyClassDecl)
+	// infixl 6 +++, 优先级即 oper.BP
+	FixityDecl.Pattern = Seq(
+		Alt(Tok(INFIXL), Tok(INFIXR), Tok(INFIX)),
+		Alt(Tok(INT).Map(applyInt), Tok(FLOAT).Map(applyFloat)),
+		InfixOp,
+	).Map(applyFixity)
+	// 顶层表达式必须以 ;; 结束 (同 OCaml), 否则与之前定义的 rhs 无法区分, e.g. let x = f\nprint x 即 let x = f print x
+	// 其他顶层定义之后也可以有 ;;, 缺少 ;; 的顶层表达式是语法错误
+	TopLevel.Pattern = Alt(
+		KLeft(Alt(Bindings, TypeDecl, ClassDecl, FixityDecl), OptSc(Tok(SEMI_SEMI))),
+		KLeft(Term, Tok(SEMI_SEMI)).Map(applyTopExpr),
+	)
 	_Pgrm.Pattern = RepSc(TopLevel).Map(applyPgrm)
 }
 
@@ -348,7 +803,21 @@ func ParsePgrm(s string) (*terms.Program, error) {
 	return pgrm.(*terms.Program), nil
 }
 
-func parse(p Parser, s string) (terms.Term, error) {
+// syntaxError 语法分析之后的错误 (操作符结合、字符串插值), 由 parse 转换为解析错误
+type syntaxError struct{ msg string }
+
+func (e *syntaxError) Error() string { return e.msg }
+
+func parse(p Parser, s string) (term terms.Term, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(*syntaxError); ok {
+				term, err = nil, e
+				return
+			}
+			panic(r)
+		}
+	}()
 	toks, err := lex.Lex(s)
 	if err != nil {
 		return nil, err
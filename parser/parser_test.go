@@ -45,6 +45,20 @@ func TestParser(t *testing.T) {
 			success: true,
 			result:  `Str("Hello")`,
 		},
+		{
+			name:    "Interpolation",
+			p:       _Expr,
+			input:   `"a ${x + 1} b"`,
+			success: true,
+			result:  `Interp([Str("a ") Binary(+, INFIX_L, Var(x), Int(1)) Str(" b")])`,
+		},
+		{
+			name:    "Interpolation escape",
+			p:       _Expr,
+			input:   `"\${x}"`,
+			success: true,
+			result:  `Str("${x}")`,
+		},
 		{
 			name:    "Const true",
 			p:       _Expr,
@@ -165,11 +179,32 @@ func TestParser(t *testing.T) {
 			result:  "Rcd([{true Bool(true)} {false Bool(false)}])",
 		},
 		{
-			name:    "Tuple",
+			name:    "Record",
+			p:       _Expr,
+			input:   `{ r with x: 1, y: f z }`,
+			success: true,
+			result:  "Ext(Var(r), [{x Int(1)} {y App(Var(f) Var(z))}])",
+		},
+		{
+			name:    "Record",
+			p:       _Expr,
+			input:   `{ {a: 1} \ a with b: 2 }`,
+			success: true,
+			result:  "Ext(Rst(Rcd([{a Int(1)}]), a), [{b Int(2)}])",
+		},
+		{
+			name:    "SubTerm",
+			p:       _Expr,
+			input:   `f r \ a.b \ c`,
+			success: true,
+			result:  "App(Var(f) Rst(Sel(Rst(Var(r), a), b), c))",
+		},
+		{
+			name:    "Unit",
 			p:       _Expr,
 			input:   `()`,
 			success: true,
-			result:  "Tuple([])",
+			result:  "Unit",
 		},
 		{
 			name:    "Tuple",
@@ -297,6 +332,62 @@ func TestParser(t *testing.T) {
 			success: true,
 			result:  "App(App(Var(f1) App(Var(f2) Var(a))) Var(b))",
 		},
+		{
+			name:    "Tag",
+			p:       _Expr,
+			input:   `Some (f x)`,
+			success: true,
+			result:  "App(Var(Some) App(Var(f) Var(x)))",
+		},
+		{
+			name:    "Case",
+			p:       _Expr,
+			input:   `case o of { Some x -> f x, None -> 0 }`,
+			success: true,
+			result:  "Case(Var(o), [{Some x App(Var(f) Var(x))} {None  Int(0)}])",
+		},
+		{
+			name:    "Ref",
+			p:       _Expr,
+			input:   `r.cell := !r.cell`,
+			success: true,
+			result:  "Assign(Sel(Var(r), cell), Deref(Sel(Var(r), cell)))",
+		},
+		{
+			name:    "Ref",
+			p:       _Expr,
+			input:   `let r = ref (f x) in !r`,
+			success: true,
+			result:  "Let(r, Ref(App(Var(f) Var(x))), Deref(Var(r)))",
+		},
+		{
+			name:    "Deref",
+			p:       _Expr,
+			input:   `!!r != !r`,
+			success: true,
+			result:  "Binary(!=, INFIX_N, Deref(Deref(Var(r))), Deref(Var(r)))",
+		},
+		{
+			name:    "LogicNot",
+			p:       _Expr,
+			input:   `!(a && b) || !true || !(f x)`,
+			success: true,
+			result:  "Binary(||, INFIX_L, Binary(||, INFIX_L, Unary(!, Binary(&&, INFIX_L, Var(a), Var(b)), true), Unary(!, Bool(true), true)), Deref(App(Var(f) Var(x))))",
+		},
+		{
+			name:    "LetGroup",
+			p:       _Expr,
+			input:   `let rec f = fun x -> g x and g = fun x -> f x in f`,
+			success: true,
+			result:  "LetGroup(RecGroup(f = Fun(x, App(Var(g) Var(x))), g = Fun(x, App(Var(f) Var(x)))), Var(f))",
+		},
+		{
+			name:    "TopLevel",
+			p:       _Pgrm,
+			input:   "let rec f = g and g = f and h = f\nlet x = f",
+			success: true,
+			result:  "Program([Let(f, Var(g)) Let(g, Var(f)) Let(h, Var(f)) LetRec(x, Var(f))])",
+		},
 		{
 			name:    "TopLevel",
 			p:       _Pgrm,
@@ -304,6 +395,211 @@ func TestParser(t *testing.T) {
 			success: true,
 			result:  "Program([LetRec(twice, Fun(f, Fun(x, App(Var(f) App(Var(f) Var(x))))))])",
 		},
+		{
+			name:    "TypeDecl",
+			p:       _Pgrm,
+			input:   "type nat <: int\ntype uuid\nlet n = 1",
+			success: true,
+			result:  "Program([Prim(nat <: int) Prim(uuid)], [LetRec(n, Int(1))])",
+		},
+		{
+			name:    "CtorDecl",
+			p:       _Pgrm,
+			input:   "type Box[A] = { value: A }\ntype Cell[in A, out B]\ntype Pipe[A] = Sink[Sink[A]] -> unit",
+			success: true,
+			result:  "Program([Ctor(Box[A] = {value: A}) Ctor(Cell[in A, out B]) Ctor(Pipe[A] = Sink[Sink[A]] -> unit)], [])",
+		},
+		{
+			name:    "Operators",
+			p:       _Expr,
+			input:   "1 + 2 * 3",
+			success: true,
+			result:  "Binary(+, INFIX_L, Int(1), Binary(*, INFIX_L, Int(2), Int(3)))",
+		},
+		{
+			name:    "Operators",
+			p:       _Expr,
+			input:   "a - b - c",
+			success: true,
+			result:  "Binary(-, INFIX_L, Binary(-, INFIX_L, Var(a), Var(b)), Var(c))",
+		},
+		{
+			name:    "Operators",
+			p:       _Expr,
+			input:   "1-2",
+			success: true,
+			result:  "Binary(-, INFIX_L, Int(1), Int(2))",
+		},
+		{
+			name:    "Operators",
+			p:       _Expr,
+			input:   "f x + -g y",
+			success: true,
+			result:  "Binary(+, INFIX_L, App(Var(f) Var(x)), Unary(neg, App(Var(g) Var(y)), true))",
+		},
+		{
+			name:    "Operators",
+			p:       _Expr,
+			input:   "not a && b || c == d",
+			success: true,
+			result:  "Binary(||, INFIX_L, Binary(&&, INFIX_L, Unary(not, Var(a), true), Var(b)), Binary(==, INFIX_N, Var(c), Var(d)))",
+		},
+		{
+			name:    "Operators",
+			p:       _Expr,
+			input:   "-x * 2.5 <= y",
+			success: true,
+			result:  "Binary(<=, INFIX_N, Binary(*, INFIX_L, Unary(neg, Var(x), true), Float(2.500000)), Var(y))",
+		},
+		{
+			name:    "Operators",
+			p:       _Expr,
+			input:   "r := n + 1",
+			success: true,
+			result:  "Assign(Var(r), Binary(+, INFIX_L, Var(n), Int(1)))",
+		},
+		{
+			name:    "Alias",
+			p:       _Pgrm,
+			input:   "type Point = { x: int, y: int }\ntype Stream[A] = { head: A, tail: unit -> Stream[A] }",
+			success: true,
+			result:  "Program([Ctor(Point = {x: int, y: int}) Ctor(Stream[A] = {head: A, tail: unit -> Stream[A]})], [])",
+		},
+		{
+			name:    "Ascribe",
+			p:       _Expr,
+			input:   "(f x : Stream[int])",
+			success: true,
+			result:  "Asc(App(Var(f) Var(x)), Stream[int])",
+		},
+		{
+			name:    "OptionalField",
+			p:       _Pgrm,
+			input:   "type Config = {port?: int, host: string}",
+			success: true,
+			result:  "Program([Ctor(Config = {port?: int, host: string})], [])",
+		},
+		{
+			name:    "Coalesce",
+			p:       _Expr,
+			input:   "r.port ?? c.port ?? 8000 + 80 || x",
+			success: true,
+			result:  "Coalesce(Var(r), port, Coalesce(Var(c), port, Binary(||, INFIX_L, Binary(+, INFIX_L, Int(8000), Int(80)), Var(x))))",
+		},
+		{
+			name:    "Dynamic",
+			p:       _Expr,
+			input:   "(host : ? -> {x: ?})",
+			success: true,
+			result:  "Asc(Var(host), ? -> {x: ?})",
+		},
+		// ∨ 比 -> 结合得紧
+		{
+			name:    "LiteralUnion",
+			p:       _Expr,
+			input:   `(c : {method: "GET" ∨ "POST", port: 80 ∨ 443 ∨ 8080} ∨ 1.5 -> int)`,
+			success: true,
+			result:  `Asc(Var(c), {method: "GET" ∨ "POST", port: 80 ∨ 443 ∨ 8080} ∨ 1.5 -> int)`,
+		},
+		{
+			name:    "LiteralUnion",
+			p:       _Pgrm,
+			input:   `type Method = "GET" ∨ "POST"` + "\ntype F = (int -> int) ∨ bool",
+			success: true,
+			result:  `Program([Ctor(Method = "GET" ∨ "POST") Ctor(F = (int -> int) ∨ bool)], [])`,
+		},
+		{
+			name:    "ClassDecl",
+			p:       _Pgrm,
+			input:   "trait Showable\nclass Point: Base, Showable { x: int, f: (int -> int) -> {y: int} }\nclass Unit {}",
+			success: true,
+			result:  "Program([Trait(Showable) Class(Point: Base, Showable, {x: int, f: (int -> int) -> {y: int}}) Class(Unit, {})], [])",
+		},
+		{
+			name:    "FixityDecl",
+			p:       _Pgrm,
+			input:   "infixl 8.5 +++\ninfixr 3 <|\nlet (+++) = f\nlet x = a +++ b * c <| d <| e || g",
+			success: true,
+			result:  "Program([LetRec(+++, Var(f)) LetRec(x, Binary(<|, INFIX_R, Binary(+++, INFIX_L, Var(a), Binary(*, INFIX_L, Var(b), Var(c))), Binary(<|, INFIX_R, Var(d), Binary(||, INFIX_L, Var(e), Var(g)))))])",
+		},
+		{
+			name:    "FixityDecl",
+			p:       _Pgrm,
+			input:   "infixl 8 +++\nlet x = a +++ b\ninfixl 10 +++\nlet y = a + b +++ c",
+			success: true,
+			result:  "Program([LetRec(x, Binary(+++, INFIX_L, Var(a), Var(b))) LetRec(y, Binary(+, INFIX_L, Var(a), Binary(+++, INFIX_L, Var(b), Var(c))))])",
+		},
+		{
+			name:    "Section",
+			p:       _Expr,
+			input:   "(+)",
+			success: true,
+			result:  "Var(+)",
+		},
+		{
+			name:    "Section",
+			p:       _Expr,
+			input:   "(* a + b)",
+			success: true,
+			result:  "Fun($x, Binary(*, INFIX_L, Var($x), Binary(+, INFIX_L, Var(a), Var(b))))",
+		},
+		{
+			name:    "Section",
+			p:       _Expr,
+			input:   "(f x -)",
+			success: true,
+			result:  "Fun($x, Binary(-, INFIX_L, App(Var(f) Var(x)), Var($x)))",
+		},
+		{
+			name:    "Section",
+			p:       _Expr,
+			input:   "(- 1)",
+			success: true,
+			result:  "Int(-1)",
+		},
+		{
+			name:    "Projection",
+			p:       _Expr,
+			input:   "f t._2._10 r._0",
+			success: true,
+			result:  "App(App(Var(f) Proj(Proj(Var(t), 2), 10)) Sel(Var(r), _0))",
+		},
+		{
+			name:    "Destruct",
+			p:       _Expr,
+			input:   "let (a, b) = f x in let {x, y} = r in a + y",
+			success: true,
+			result:  "Destruct(Tuple[a b], App(Var(f) Var(x)), Destruct(Rcd[x y], Var(r), Binary(+, INFIX_L, Var(a), Var(y))))",
+		},
+		{
+			name:    "Sequence",
+			p:       _Expr,
+			input:   "r := 1; let x = !r in f x; g (); x",
+			success: true,
+			result:  "Seq(Assign(Var(r), Int(1)), Let(x, Deref(Var(r)), Seq(App(Var(f) Var(x)), Seq(App(Var(g) Unit), Var(x)))))",
+		},
+		{
+			name:    "Sequence",
+			p:       _Expr,
+			input:   "if b then f x else (g x; h x)",
+			success: true,
+			result:  "If(Var(b), App(Var(f) Var(x)), Seq(App(Var(g) Var(x)), App(Var(h) Var(x))))",
+		},
+		{
+			name:    "TopLevel",
+			p:       _Pgrm,
+			input:   "let r = ref 0;;\nr := !r + 1;;\nlet x = !r;;\nf x; g x;;",
+			success: true,
+			result:  "Program([LetRec(r, Ref(Int(0))) Expr(Assign(Var(r), Binary(+, INFIX_L, Deref(Var(r)), Int(1)))) LetRec(x, Deref(Var(r))) Expr(Seq(App(Var(f) Var(x)), App(Var(g) Var(x))))])",
+		},
+		// 没有 ;; 时顶层表达式接在之前定义的 rhs 之后
+		{
+			name:    "TopLevel",
+			p:       _Pgrm,
+			input:   "let x = f\nprint x",
+			success: true,
+			result:  "Program([LetRec(x, App(App(Var(f) Var(print)) Var(x)))])",
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			toks := lex.MustLex(tt.input)
@@ -333,6 +629,105 @@ func TestParser(t *testing.T) {
 	}
 }
 
+func TestUndeclaredOperator(t *testing.T) {
+	_, err := ParsePgrm("let x = 1 +++ 2\ninfixl 8 +++")
+	if err == nil || err.Error() != "undeclared infix operator: +++" {
+		t.Errorf("expect undeclared infix operator: +++ actual %v", err)
+	}
+}
+
+func TestNonAssociative(t *testing.T) {
+	for _, tt := range []struct {
+		input, error string
+	}{
+		{"let x = a < b < c", "non-associative operator < cannot be chained with <"},
+		{"let x = 1 == 2 == true", "non-associative operator == cannot be chained with =="},
+		{"let x = a == b != c", "non-associative operator == cannot be chained with !="},
+	} {
+		_, err := ParsePgrm(tt.input)
+		if err == nil || err.Error() != tt.error {
+			t.Errorf("expect %s actual %v", tt.error, err)
+		}
+	}
+	// 不同级的不结合操作符可以组合
+	if _, err := ParsePgrm("let x = a < b == c > d"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCoalesceOperand(t *testing.T) {
+	_, err := ParsePgrm("let x = f r ?? 1")
+	if err == nil || err.Error() != "left operand of ?? must be a field selection: App(Var(f) Var(r))" {
+		t.Errorf("expect left operand of ?? must be a field selection actual %v", err)
+	}
+}
+
+func TestReservedKeywords(t *testing.T) {
+	for _, kw := range []string{
+		"let", "rec", "and", "in", "fun", "if", "then", "else", "not", "case", "of", "ref", "with",
+		"type", "class", "trait", "infixl", "infixr", "infix",
+	} {
+		if _, err := ParsePgrm("let " + kw + " = 1"); err == nil {
+			t.Errorf("expect %s to be reserved", kw)
+		}
+	}
+	// out 只在型变标注的位置有特殊含义
+	pgrm, err := ParsePgrm("type F[out, out A, in B]\nlet out = fun out -> {out: out}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Program([Ctor(F[out, out A, in B])], [LetRec(out, Fun(out, Rcd([{out Var(out)}])))])"
+	if actual := pgrm.String(); actual != expected {
+		t.Errorf("expect %s actual %s", expected, actual)
+	}
+	_, err = ParsePgrm("type F[inout A]")
+	if err == nil || err.Error() != "invalid variance of type parameter A: inout" {
+		t.Errorf("expect invalid variance of type parameter A: inout actual %v", err)
+	}
+}
+
+func TestLiteralType(t *testing.T) {
+	_, err := ParsePgrm(`let x = (y : "a${b}")`)
+	if err == nil || err.Error() != `invalid literal type: "a${b}"` {
+		t.Errorf(`expect invalid literal type: "a${b}" actual %v`, err)
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	for _, tt := range []struct {
+		pgrm     string
+		expected string
+	}{
+		{`let x = "a ${1} b"`, `LetRec(x, App(App(Var(concat) App(App(Var(concat) Str("a ")) App(Var(toString) Int(1)))) Str(" b")))`},
+		{`let x = "${{a: 1}.a}"`, `LetRec(x, App(Var(toString) Sel(Rcd([{a Int(1)}]), a)))`},
+		// 插值表达式中的字符串
+		{`let x = "${f "}" "b"}"`, `LetRec(x, App(Var(toString) App(App(Var(f) Str("}")) Str("b"))))`},
+		{`let x = "${"${1}"}"`, `LetRec(x, App(Var(toString) App(Var(toString) Int(1))))`},
+	} {
+		pgrm, err := ParsePgrm(tt.pgrm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := fmt.Sprintf("%s", Desugar(pgrm.Defs[0])); actual != tt.expected {
+			t.Errorf("expect %s actual %s", tt.expected, actual)
+		}
+	}
+
+	for _, tt := range []struct {
+		pgrm     string
+		expected string
+	}{
+		{`let x = "a ${b"`, `unclosed interpolation in string literal: "a ${b"`},
+		// 只检查稳定的前缀, 之后是解析器的内部错误
+		{`let x = "${let}"`, "invalid interpolation ${let}: "},
+	} {
+		_, err := ParsePgrm(tt.pgrm)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("expect %s actual %v", tt.expected, err)
+		}
+	}
+}
+
 func succeed(out Output) []Result {
 	if out.Success {
 		return out.Candidates
@@ -20,6 +20,7 @@ func Bool(val bool) *LiteralBool                 { return &LiteralBool{Val: val}
 func Int(val int64) *LiteralInt                  { return &LiteralInt{Val: val} }
 func Float(val float64) *LiteralFloat            { return &LiteralFloat{Val: val} }
 func Str(val string) *LiteralString              { return &LiteralString{Val: val} }
+func Unit() *LiteralUnit                         { return &LiteralUnit{} }
 func Tup(xs ...Term) *Tuple                      { return &Tuple{xs} }
 func Lst(xs ...Term) *List                       { return &List{xs} }
 func Var(name string) *Variable                  { return &Variable{Name: name} }
@@ -27,19 +28,60 @@ func Lam(name string, rhs Term) *Lambda          { return &Lambda{Name: name, Rh
 func App(lhs Term, rhs Term) *Application        { return &Application{Lhs: lhs, Rhs: rhs} }
 func Rcd(xs []Field) *Record                     { return &Record{Fields: xs} }
 func Sel(recv Term, fieldName string) *Selection { return &Selection{Recv: recv, FieldName: fieldName} }
+func Proj(recv Term, index int) *Projection      { return &Projection{Recv: recv, Index: index} }
+func Ext(recv Term, xs []Field) *Extend          { return &Extend{Recv: recv, Fields: xs} }
+func Rst(recv Term, fieldName string) *Restrict  { return &Restrict{Recv: recv, FieldName: fieldName} }
+func Tg(name string, arg Term) *Tag              { return &Tag{Name: name, Arg: arg} }
+func Cas(scrut Term, arms []Arm) *Case           { return &Case{Scrut: scrut, Arms: arms} }
+func NRef(init Term) *NewRef                     { return &NewRef{Init: init} }
+func Drf(ref Term) *Deref                        { return &Deref{Ref: ref} }
+func Asgn(ref Term, rhs Term) *Assign            { return &Assign{Ref: ref, Rhs: rhs} }
+func Asc(term Term, ty TypeExpr) *Ascribe        { return &Ascribe{Term: term, Type: ty} }
 func Let(name string, rhs Term, body Term, rec bool) *LetDefine {
 	return &LetDefine{*Decl(name, rhs, rec), body}
 }
+func Coal(recv Term, fieldName string, dflt Term) *Coalesce {
+	return &Coalesce{Recv: recv, FieldName: fieldName, Default: dflt}
+}
 
-func Pgrm(defs []*Declaration) *Program { return &Program{Defs: defs} }
+func Pgrm(defs []*Declaration) *Program    { return &Program{Defs: defs} }
+func PrimDcl(name, super string) *PrimDecl { return &PrimDecl{Name: name, Super: super} }
+func ClassDcl(trait bool, name string, parents []string, fields []TyField) *ClassDecl {
+	return &ClassDecl{Trait: trait, Name: name, Parents: parents, Fields: fields}
+}
+func TyNm(name string) *TyName         { return &TyName{Name: name} }
+func TyFn(lhs, rhs TypeExpr) *TyFun    { return &TyFun{Lhs: lhs, Rhs: rhs} }
+func TyRcd(fields []TyField) *TyRecord { return &TyRecord{Fields: fields} }
+func CtorDcl(name string, params []TyParam, body TypeExpr) *CtorDecl {
+	return &CtorDecl{Name: name, Params: params, Body: body}
+}
+func TyAp(name string, args []TypeExpr) *TyApp { return &TyApp{Name: name, Args: args} }
+func TyLt(lit Term) *TyLit                     { return &TyLit{Lit: lit} }
+func TyUn(lhs, rhs TypeExpr) *TyUnion          { return &TyUnion{Lhs: lhs, Rhs: rhs} }
+func RecGrp(defs []*Declaration) *RecGroup {
+	g := &RecGroup{Defs: defs}
+	for _, def := range defs {
+		def.Rec = true
+		def.Group = g
+	}
+	return g
+}
+func LetGrp(defs []*Declaration, body Term) *LetGroup { return &LetGroup{*RecGrp(defs), body} }
 func Decl(name string, rhs Term, rec bool) *Declaration {
 	return &Declaration{Name: name, Rhs: rhs, Rec: rec}
 }
+func TopExpr(term Term) *Declaration { return Decl("", term, false) }
 
 func Grp(term Term) *Group                                   { return &Group{term} }
 func Iff(cond, then, els Term) *If                           { return &If{cond, then, els} }
 func Un(name string, term Term, prefix bool) *Unary          { return &Unary{name, term, prefix} }
 func Bin(name string, bp oper.Fixity, lhs, rhs Term) *Binary { return &Binary{name, bp, lhs, rhs} }
+func Ops(operands []Term, ops []string) *Operators           { return &Operators{operands, ops} }
+func Sq(lhs, rhs Term) *Sequence                             { return &Sequence{lhs, rhs} }
+func Interp(parts ...Term) *Interpolation                    { return &Interpolation{parts} }
+func Dstr(names []string, record bool, rhs, body Term) *Destruct {
+	return &Destruct{names, record, rhs, body}
+}
 
 func LamN(xs []string, rhs Term) *Lambda {
 	argc := len(xs)
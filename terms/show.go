@@ -9,15 +9,56 @@ import (
 
 func ShowPgrm(p *Program) string {
 	var b strings.Builder
+	for _, decl := range p.Types {
+		b.WriteString(ShowTypeDecl(decl))
+		b.WriteString("\n")
+	}
 	for _, def := range p.Defs {
-		b.WriteString(ShowDef(def))
+		if def.Group != nil && def != def.Group.Defs[0] {
+			b.WriteString(fmt.Sprintf("and %s = %s", def.Name, showTerm(def.Rhs, 0)))
+		} else {
+			b.WriteString(ShowDef(def))
+		}
 		b.WriteString("\n")
 	}
 	return b.String()
 }
 
+func ShowTypeDecl(decl TypeDecl) string {
+	switch d := decl.(type) {
+	case *PrimDecl:
+		if d.Super == "" {
+			return "type " + d.Name
+		}
+		return fmt.Sprintf("type %s <: %s", d.Name, d.Super)
+	case *CtorDecl:
+		if d.Body == nil {
+			return "type " + d.head()
+		}
+		return fmt.Sprintf("type %s = %s", d.head(), d.Body)
+	case *ClassDecl:
+		kind := "class"
+		if d.Trait {
+			kind = "trait"
+		}
+		s := kind + " " + d.Name
+		if len(d.Parents) != 0 {
+			s += ": " + strings.Join(d.Parents, ", ")
+		}
+		if d.Fields != nil {
+			s += " " + TyRcd(d.Fields).String()
+		}
+		return s
+	default:
+		panic("unreached")
+	}
+}
+
 func ShowDef(def *Declaration) string {
 	rhs := showTerm(def.Rhs, 0)
+	if def.Name == "" {
+		return rhs + ";;"
+	}
 	if def.Rec {
 		return fmt.Sprintf("let rec %s = %s", def.Name, rhs)
 	} else {
@@ -39,6 +80,8 @@ func showTerm(term Term, outerPrec int) string {
 		return strconv.FormatFloat(t.Val, 'g', -1, 64)
 	case *LiteralString:
 		return fmt.Sprintf("%q", t.Val)
+	case *LiteralUnit:
+		return "()"
 	case *Variable:
 		return t.Name
 	case *Lambda:
@@ -70,6 +113,19 @@ func showTerm(term Term, outerPrec int) string {
 		return util.JoinStr(xs, ", ", "{", "}")
 	case *Selection:
 		return fmt.Sprintf("%s.%s", showTerm(t.Recv, 30), t.FieldName)
+	case *Projection:
+		return fmt.Sprintf("%s._%d", showTerm(t.Recv, 30), t.Index)
+	case *Coalesce:
+		coal := fmt.Sprintf("%s.%s ?? %s", showTerm(t.Recv, 30), t.FieldName, showTerm(t.Default, 10))
+		return parensIf(coal, outerPrec > 10)
+	case *Extend:
+		xs := make([]string, len(t.Fields))
+		for i, fd := range t.Fields {
+			xs[i] = fmt.Sprintf("%s: %s", fd.Name, showTerm(fd.Term, 0))
+		}
+		return fmt.Sprintf("{%s with %s}", showTerm(t.Recv, 0), strings.Join(xs, ", "))
+	case *Restrict:
+		return fmt.Sprintf("%s \\ %s", showTerm(t.Recv, 30), t.FieldName)
 	case *LetDefine:
 		body := showTerm(t.Body, 0)
 		rhs := showTerm(t.Rhs, 0)
@@ -78,6 +134,38 @@ func showTerm(term Term, outerPrec int) string {
 		} else {
 			return fmt.Sprintf("let %s = %s in %s", t.Name, rhs, body)
 		}
+	case *Tag:
+		if t.Arg == nil {
+			return t.Name
+		}
+		tag := fmt.Sprintf("%s %s", t.Name, showTerm(t.Arg, 30))
+		return parensIf(tag, outerPrec >= 20) // 作为 application 的参数需要括号
+	case *LetGroup:
+		xs := make([]string, len(t.Defs))
+		for i, def := range t.Defs {
+			xs[i] = fmt.Sprintf("%s = %s", def.Name, showTerm(def.Rhs, 0))
+		}
+		return fmt.Sprintf("let rec %s in %s", strings.Join(xs, " and "), showTerm(t.Body, 0))
+	case *NewRef:
+		return parensIf("ref "+showTerm(t.Init, 30), outerPrec >= 20)
+	case *Deref:
+		return "!" + showTerm(t.Ref, 30)
+	case *Ascribe:
+		return fmt.Sprintf("(%s : %s)", showTerm(t.Term, 0), t.Type)
+	case *Assign:
+		asgn := fmt.Sprintf("%s := %s", showTerm(t.Ref, 20), showTerm(t.Rhs, 10))
+		return parensIf(asgn, outerPrec > 10)
+	case *Case:
+		xs := make([]string, len(t.Arms))
+		for i, arm := range t.Arms {
+			pat := arm.Tag
+			if arm.Name != "" {
+				pat += " " + arm.Name
+			}
+			xs[i] = fmt.Sprintf("%s -> %s", pat, showTerm(arm.Body, 0))
+		}
+		cas := fmt.Sprintf("case %s of %s", showTerm(t.Scrut, 0), util.JoinStr(xs, ", ", "{ ", " }"))
+		return parensIf(cas, outerPrec > 10)
 	default:
 		panic("unreached")
 	}
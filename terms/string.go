@@ -2,12 +2,14 @@ package terms
 
 import (
 	"fmt"
+	"strings"
 )
 
 func (l *LiteralInt) String() string    { return fmt.Sprintf("Int(%d)", l.Val) }
 func (l *LiteralBool) String() string   { return fmt.Sprintf("Bool(%t)", l.Val) }
 func (l *LiteralFloat) String() string  { return fmt.Sprintf("Float(%f)", l.Val) }
 func (l *LiteralString) String() string { return fmt.Sprintf("Str(%q)", l.Val) }
+func (l *LiteralUnit) String() string   { return "Unit" }
 func (v *Variable) String() string      { return fmt.Sprintf("Var(%s)", v.Name) }
 func (l *Lambda) String() string        { return fmt.Sprintf("Fun(%s, %s)", l.Name, l.Rhs) }
 func (a *Application) String() string   { return fmt.Sprintf("App(%s %s)", a.Lhs, a.Rhs) }
@@ -16,6 +18,21 @@ func (t *List) String() string          { return fmt.Sprintf("List(%s)", t.Elms)
 func (f *Field) String() string         { return fmt.Sprintf("Field(%s, %s)", f.Name, f.Term) }
 func (r *Record) String() string        { return fmt.Sprintf("Rcd(%s)", r.Fields) }
 func (s *Selection) String() string     { return fmt.Sprintf("Sel(%s, %s)", s.Recv.String(), s.FieldName) }
+func (p *Projection) String() string    { return fmt.Sprintf("Proj(%s, %d)", p.Recv, p.Index) }
+func (e *Extend) String() string        { return fmt.Sprintf("Ext(%s, %s)", e.Recv, e.Fields) }
+func (r *Restrict) String() string      { return fmt.Sprintf("Rst(%s, %s)", r.Recv, r.FieldName) }
+func (a *Arm) String() string           { return fmt.Sprintf("Arm(%s, %s, %s)", a.Tag, a.Name, a.Body) }
+func (c *Case) String() string          { return fmt.Sprintf("Case(%s, %s)", c.Scrut, c.Arms) }
+func (r *NewRef) String() string        { return fmt.Sprintf("Ref(%s)", r.Init) }
+func (d *Deref) String() string         { return fmt.Sprintf("Deref(%s)", d.Ref) }
+func (a *Assign) String() string        { return fmt.Sprintf("Assign(%s, %s)", a.Ref, a.Rhs) }
+func (a *Ascribe) String() string       { return fmt.Sprintf("Asc(%s, %s)", a.Term, a.Type) }
+func (t *Tag) String() string {
+	if t.Arg == nil {
+		return fmt.Sprintf("Tag(%s)", t.Name)
+	}
+	return fmt.Sprintf("Tag(%s, %s)", t.Name, t.Arg)
+}
 func (l *LetDefine) String() string {
 	if l.Rec {
 		return fmt.Sprintf("LetRec(%s, %s, %s)", l.Name, l.Rhs, l.Body)
@@ -23,16 +40,130 @@ func (l *LetDefine) String() string {
 		return fmt.Sprintf("Let(%s, %s, %s)", l.Name, l.Rhs, l.Body)
 	}
 }
+func (c *Coalesce) String() string {
+	return fmt.Sprintf("Coalesce(%s, %s, %s)", c.Recv, c.FieldName, c.Default)
+}
 
 func (g *Group) String() string { return fmt.Sprintf("Group(%s)", g.Term) }
 func (i *If) String() string    { return fmt.Sprintf("If(%s, %s, %s)", i.Cond, i.Then, i.Else) }
 func (u *Unary) String() string { return fmt.Sprintf("Unary(%s, %s, %t)", u.Name, u.Rhs, u.Prefix) }
+func (o *Operators) String() string {
+	xs := make([]string, len(o.Operands))
+	for i, operand := range o.Operands {
+		xs[i] = operand.String()
+	}
+	return fmt.Sprintf("Operators([%s], %v)", strings.Join(xs, ", "), o.Ops)
+}
+func (s *Sequence) String() string      { return fmt.Sprintf("Seq(%s, %s)", s.Lhs, s.Rhs) }
+func (i *Interpolation) String() string { return fmt.Sprintf("Interp(%s)", i.Parts) }
+func (d *Destruct) String() string {
+	kind := "Tuple"
+	if d.Record {
+		kind = "Rcd"
+	}
+	return fmt.Sprintf("Destruct(%s%v, %s, %s)", kind, d.Names, d.Rhs, d.Body)
+}
 func (b *Binary) String() string {
 	return fmt.Sprintf("Binary(%s, %s, %s, %s)", b.Name, b.Fixity, b.Lhs, b.Rhs)
 }
 
-func (p Program) String() string { return fmt.Sprintf("Program(%s)", p.Defs) }
+func (g *RecGroup) String() string {
+	xs := make([]string, len(g.Defs))
+	for i, def := range g.Defs {
+		xs[i] = fmt.Sprintf("%s = %s", def.Name, def.Rhs)
+	}
+	return fmt.Sprintf("RecGroup(%s)", strings.Join(xs, ", "))
+}
+func (l *LetGroup) String() string { return fmt.Sprintf("LetGroup(%s, %s)", &l.RecGroup, l.Body) }
+
+func (p Program) String() string {
+	if len(p.Types) == 0 {
+		return fmt.Sprintf("Program(%s)", p.Defs)
+	}
+	return fmt.Sprintf("Program(%s, %s)", p.Types, p.Defs)
+}
+func (p *PrimDecl) String() string {
+	if p.Super == "" {
+		return fmt.Sprintf("Prim(%s)", p.Name)
+	}
+	return fmt.Sprintf("Prim(%s <: %s)", p.Name, p.Super)
+}
+func (c *ClassDecl) String() string {
+	kind := "Class"
+	if c.Trait {
+		kind = "Trait"
+	}
+	head := c.Name
+	if len(c.Parents) != 0 {
+		head += ": " + strings.Join(c.Parents, ", ")
+	}
+	if c.Fields == nil {
+		return fmt.Sprintf("%s(%s)", kind, head)
+	}
+	return fmt.Sprintf("%s(%s, %s)", kind, head, TyRcd(c.Fields))
+}
+func (c *CtorDecl) String() string {
+	if c.Body == nil {
+		return fmt.Sprintf("Ctor(%s)", c.head())
+	}
+	return fmt.Sprintf("Ctor(%s = %s)", c.head(), c.Body)
+}
+func (c *CtorDecl) head() string {
+	xs := make([]string, len(c.Params))
+	for i, p := range c.Params {
+		if p.Variance == "" {
+			xs[i] = p.Name
+		} else {
+			xs[i] = p.Variance + " " + p.Name
+		}
+	}
+	if len(xs) == 0 {
+		return c.Name
+	}
+	return c.Name + "[" + strings.Join(xs, ", ") + "]"
+}
+func (t *TyName) String() string { return t.Name }
+func (t *TyApp) String() string {
+	xs := make([]string, len(t.Args))
+	for i, arg := range t.Args {
+		xs[i] = arg.String()
+	}
+	return t.Name + "[" + strings.Join(xs, ", ") + "]"
+}
+func (t *TyFun) String() string {
+	if _, ok := t.Lhs.(*TyFun); ok {
+		return fmt.Sprintf("(%s) -> %s", t.Lhs, t.Rhs)
+	}
+	return fmt.Sprintf("%s -> %s", t.Lhs, t.Rhs)
+}
+func (t *TyLit) String() string { return ShowTerm(t.Lit) }
+func (t *TyUnion) String() string {
+	xs := []TypeExpr{t.Lhs, t.Rhs}
+	ys := make([]string, len(xs))
+	for i, x := range xs {
+		if _, ok := x.(*TyFun); ok {
+			ys[i] = fmt.Sprintf("(%s)", x)
+		} else {
+			ys[i] = x.String()
+		}
+	}
+	return ys[0] + " ∨ " + ys[1]
+}
+func (t *TyRecord) String() string {
+	xs := make([]string, len(t.Fields))
+	for i, fd := range t.Fields {
+		if fd.Optional {
+			xs[i] = fmt.Sprintf("%s?: %s", fd.Name, fd.Type)
+		} else {
+			xs[i] = fmt.Sprintf("%s: %s", fd.Name, fd.Type)
+		}
+	}
+	return "{" + strings.Join(xs, ", ") + "}"
+}
 func (d Declaration) String() string {
+	if d.Name == "" {
+		return fmt.Sprintf("Expr(%s)", d.Rhs)
+	}
 	if d.Rec {
 		return fmt.Sprintf("Let(%s, %s)", d.Name, d.Rhs)
 	} else {
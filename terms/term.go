@@ -9,9 +9,10 @@ import (
 
 // Term Language
 // 𝑡 ::= 𝑥 | 𝜆𝑥. 𝑡 | 𝑡 𝑡 | { 𝑙0 = 𝑡 ; ... ; 𝑙𝑛 = 𝑡 } | 𝑡.𝑙 | let rec 𝑥 = 𝑡 in 𝑡
+//      | 𝐶 𝑡 | case 𝑡 of { 𝐶0 𝑥 -> 𝑡, ..., 𝐶𝑛 𝑥 -> 𝑡 } | ref 𝑡 | !𝑡 | 𝑡 := 𝑡
 
 // Type Language
-// 𝜏 ::= primitive | 𝜏 → 𝜏 | { 𝑙0 : 𝜏 ; ... ; 𝑙𝑛 : 𝜏 } | 𝛼 | ⊤ | ⊥ | 𝜏 ⊔ 𝜏 | 𝜏 ⊓ 𝜏 | 𝜇𝛼. 𝜏
+// 𝜏 ::= primitive | 𝜏 → 𝜏 | { 𝑙0 : 𝜏 ; ... ; 𝑙𝑛 : 𝜏 } | [ 𝐶0 𝜏 | ... | 𝐶𝑛 𝜏 ] | Ref[in 𝜏, out 𝜏] | 𝛼 | ⊤ | ⊥ | 𝜏 ⊔ 𝜏 | 𝜏 ⊓ 𝜏 | 𝜇𝛼. 𝜏
 
 // Type System
 // Typing Rules: T-Lit, T-Var, T-Abs, T-App, T-Rcd, T-Proj, T-Sub, T-Let
@@ -37,6 +38,8 @@ type (
 	LiteralBool struct { // as in: true,false
 		Val bool
 	}
+	LiteralUnit struct { // as in: ()
+	}
 	Tuple struct { // as in: (1, 2)
 		Elms []Term
 	}
@@ -65,10 +68,58 @@ type (
 		Recv      Term
 		FieldName string
 	}
+	Projection struct { // as in: 𝑡._1, 从 1 开始
+		Recv  Term
+		Index int
+	}
+	Coalesce struct { // as in: 𝑡.a ?? 0, 缺少可选字段 a 时取默认值
+		Recv      Term
+		FieldName string
+		Default   Term
+	}
+	Extend struct { // as in: { 𝑡 with a: 0; b: true }
+		Recv   Term
+		Fields []Field
+	}
+	Restrict struct { // as in: 𝑡 \ a
+		Recv      Term
+		FieldName string
+	}
 	LetDefine struct { // let 𝑥 = 42 in 𝑥
 		Declaration
 		Body Term
 	}
+	Tag struct { // as in: Some 1, None
+		Name string
+		Arg  Term // 无参数的标签为 nil
+	}
+	Arm struct { // as in: Some x -> 𝑡
+		Tag  string
+		Name string // 不绑定参数为 ""
+		Body Term
+	}
+	Case struct { // as in: case 𝑡 of { Some x -> 𝑡, None -> 𝑡 }
+		Scrut Term
+		Arms  []Arm
+	}
+	NewRef struct { // as in: ref 𝑡
+		Init Term
+	}
+	Deref struct { // as in: !𝑡
+		Ref Term
+	}
+	Assign struct { // as in: 𝑠 := 𝑡
+		Ref Term
+		Rhs Term
+	}
+	LetGroup struct { // let rec 𝑓 = 𝑡 and 𝑔 = 𝑡 in 𝑡
+		RecGroup
+		Body Term
+	}
+	Ascribe struct { // as in: (𝑡 : Stream[int])
+		Term Term
+		Type TypeExpr
+	}
 )
 
 // parser 阶段 term 会被 desugar 处理掉
@@ -92,23 +143,142 @@ type (
 		Then Term
 		Else Term
 	}
+	// Operators 尚未按优先级结合的中缀操作符序列, Ops[i] 位于 Operands[i] 与 Operands[i+1] 之间
+	// 解析完成后按声明的优先级与结合性结合为 Binary
+	Operators struct {
+		Operands []Term
+		Ops      []string
+	}
+	Sequence struct { // as in: 𝑡; 𝑡
+		Lhs Term
+		Rhs Term
+	}
+	// Interpolation 字符串插值, Parts 是字符串字面量与插值表达式
+	Interpolation struct { // as in: "hello ${𝑡}"
+		Parts []Term
+	}
+	// Destruct 解构 tuple 或记录, Record 为 false 时 Names 依次绑定 tuple 的元素, 否则绑定同名字段
+	Destruct struct { // let (a, b) = 𝑡 in 𝑡, let {x, y} = 𝑡 in 𝑡
+		Names  []string
+		Record bool
+		Rhs    Term
+		Body   Term
+	}
 )
 
 // Declaration : Top Level Let Binding
+// 顶层表达式 (e.g. print 1;;) 是 Name 为空的 Declaration, 必须以 ;; 结束, 参见 parser 的 TopLevel
+// 没有求值器, 顶层表达式只推导类型, 不求值也不打印, 类型按顺序与定义一起由 Typer.InferTypes 返回
 type Declaration struct {
 	Rec  bool
 	Name string
 	Rhs  Term
+	// 所属的互递归定义组, 不属于任何组为 nil
+	Group *RecGroup
 }
 
-type Program struct {
+// RecGroup : let rec 𝑓 = 𝑡 and 𝑔 = 𝑡, 互递归的一组定义
+// 顶层的组成员在 Program.Defs 中依次相邻
+type RecGroup struct {
 	Defs []*Declaration
 }
 
+// TypeDecl : Top Level 类型声明
+type TypeDecl interface {
+	fmt.Stringer
+	_typeDeclNop()
+}
+
+// PrimDecl : type nat <: int, 声明基本类型及其直接父类型, 没有父类型时 Super 为 ""
+type PrimDecl struct {
+	Name  string
+	Super string
+}
+
+// ClassDecl : class Point: Showable { x: int, y: int }, trait Showable
+// 声明名义类型 (类或特质) 及其直接父类型, 字段可以省略
+type ClassDecl struct {
+	Trait   bool
+	Name    string
+	Parents []string
+	Fields  []TyField
+}
+
+// CtorDecl : type Box[A] = { value: A }, type List[out A]
+// 声明参数化的类型构造器, 没有定义体 (Body 为 nil) 的是抽象构造器
+type CtorDecl struct {
+	Name   string
+	Params []TyParam
+	Body   TypeExpr
+}
+
+// TyParam : 类型参数及其型变标注 "in", "out", 没有标注为 ""
+type TyParam struct {
+	Variance string
+	Name     string
+}
+
+// TypeExpr : 声明中的类型表达式, e.g. int, {x: int}, int -> int
+type TypeExpr interface {
+	fmt.Stringer
+	_typeExprNop()
+}
+
+type (
+	// TyName : 基本类型或名义类型, e.g. int, Point
+	TyName struct {
+		Name string
+	}
+	TyFun struct {
+		Lhs TypeExpr
+		Rhs TypeExpr
+	}
+	TyRecord struct {
+		Fields []TyField
+	}
+	// TyApp : 类型构造器的应用, e.g. Box[int]
+	TyApp struct {
+		Name string
+		Args []TypeExpr
+	}
+	// TyLit : 字面量的单例类型, e.g. "GET", 42, Lit 是整数、浮点数或字符串字面量
+	TyLit struct {
+		Lit Term
+	}
+	// TyUnion : 并集, e.g. "GET" ∨ "POST"
+	TyUnion struct {
+		Lhs TypeExpr
+		Rhs TypeExpr
+	}
+	// TyField : 记录类型的字段, 可选字段 e.g. {port?: int}
+	TyField struct {
+		Name     string
+		Type     TypeExpr
+		Optional bool
+	}
+)
+
+type Program struct {
+	Types []TypeDecl
+	Defs  []*Declaration
+}
+
+func (_ *PrimDecl) _typeDeclNop()  {}
+func (_ *ClassDecl) _typeDeclNop() {}
+func (_ *CtorDecl) _typeDeclNop()  {}
+
+func (_ *TyName) _typeExprNop()   {}
+func (_ *TyFun) _typeExprNop()    {}
+func (_ *TyRecord) _typeExprNop() {}
+func (_ *TyApp) _typeExprNop()    {}
+func (_ *TyLit) _typeExprNop()    {}
+func (_ *TyUnion) _typeExprNop()  {}
+
 func (_ *LiteralInt) _termNop()    {}
 func (_ *LiteralBool) _termNop()   {}
 func (_ *LiteralFloat) _termNop()  {}
 func (_ *LiteralString) _termNop() {}
+func (_ *LiteralUnit) _termNop()   {}
 func (_ *Tuple) _termNop()         {}
 func (_ *List) _termNop()          {}
 func (_ *Variable) _termNop()      {}
@@ -116,12 +286,28 @@ func (_ *Lambda) _termNop()        {}
 func (_ *Application) _termNop()   {}
 func (_ *Record) _termNop()        {}
 func (_ *Selection) _termNop()     {}
+func (_ *Projection) _termNop()    {}
+func (_ *Coalesce) _termNop()      {}
 func (_ *LetDefine) _termNop()     {}
+func (_ *Tag) _termNop()           {}
+func (_ *Case) _termNop()          {}
+func (_ *Extend) _termNop()        {}
+func (_ *Restrict) _termNop()      {}
+func (_ *NewRef) _termNop()        {}
+func (_ *Deref) _termNop()         {}
+func (_ *Assign) _termNop()        {}
+func (_ *Ascribe) _termNop()       {}
+func (_ *LetGroup) _termNop()      {}
 
-func (_ *If) _termNop()     {}
-func (_ *Group) _termNop()  {}
-func (_ *Unary) _termNop()  {}
-func (_ *Binary) _termNop() {}
+func (_ *If) _termNop()            {}
+func (_ *Group) _termNop()         {}
+func (_ *Unary) _termNop()         {}
+func (_ *Binary) _termNop()        {}
+func (_ *Operators) _termNop()     {}
+func (_ *Destruct) _termNop()      {}
+func (_ *Sequence) _termNop()      {}
+func (_ *Interpolation) _termNop() {}
 
 func (_ *Program) _termNop()     {}
 func (_ *Declaration) _termNop() {}
+func (_ *RecGroup) _termNop()    {}
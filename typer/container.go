@@ -221,6 +221,11 @@ func (c compactTypeThunk) Equals(that compactTypeThunk) bool {
 	return reflect.ValueOf(c).Pointer() == reflect.ValueOf(that).Pointer()
 }
 
+// compactFunThunk 构造器参数的两个分量
+type compactFunThunk struct {
+	lhs, rhs compactTypeThunk
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 type nameCompactThunkMap map[string]compactTypeThunk // Map[string, compactTypeThunk]
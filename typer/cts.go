@@ -5,6 +5,7 @@ import (
 	"github.com/goghcrow/simple-sub/util"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 // Compact types representation, useful for simplification
@@ -18,6 +19,36 @@ type compactFun struct {
 	lhs, rhs *compactType
 }
 
+// compactExt 记录扩展, 只出现在正极
+type compactExt struct {
+	base    *compactType
+	fields  *sortedNameCompactMap
+	removed []string
+}
+
+// shape 扩展与删除的字段名, 形状相同的扩展可以逐字段合并
+func (e *compactExt) shape() string {
+	return strings.Join(e.fields.Keys(), ",") + "\\" + strings.Join(e.removed, ",")
+}
+
+func (e *compactExt) stringify(f func(*compactType) string) string {
+	base := f(e.base)
+	for _, name := range e.removed {
+		base += " \\ " + name
+	}
+	xs := make([]string, e.fields.Len())
+	for i, name := range e.fields.Keys() {
+		xs[i] = fmt.Sprintf("%s: %s", name, f(e.fields.Get(name)))
+	}
+	return fmt.Sprintf("{%s with %s}", base, strings.Join(xs, ", "))
+}
+
+// compactCtor 构造器的应用, 每个参数的 lhs 为逆变分量, rhs 为协变分量
+type compactCtor struct {
+	name string
+	args []*compactFun
+}
+
 type compactTypeOrVariable interface {
 	polHash(polarity bool) string
 }
@@ -28,7 +59,18 @@ type compactType struct {
 	prim *sortedPrimSet        // primitive
 	tup  []*compactType        // tuple
 	rec  *sortedNameCompactMap // record
+	opt  map[string]bool       // rec 中的可选字段
+	ext  []*compactExt         // record extension, 形状不同的扩展无法合并, 保留为并集
+	vnt  *sortedNameCompactMap // variant
+	ref  *compactFun           // reference, lhs 为写入类型 (逆变), rhs 为读取类型 (协变)
 	fun  *compactFun           // function
+	ctor []*compactCtor        // type constructor, 按名字排序, 同名构造器合并
+	// 重载交集, 成员是封闭类型, 不参与化简, 参见 ts_overload.go
+	inter []*Intersection
+	// 类型标注中的并集, 与 inter 相同, 参见 ts_union.go
+	uni []*Union
+	// 否定类型, 化简时与基本类型一起规范化, 参见 normalizeNegs
+	neg []*Negation
 
 	_hash string
 }
@@ -40,8 +82,30 @@ func emptyCompactType() *compactType {
 	}
 }
 
+// fieldName 可选字段带 ? 后缀, e.g. port?
+func (c *compactType) fieldName(name string) string {
+	if c.opt[name] {
+		return name + "?"
+	}
+	return name
+}
+
+// optionalFields 记录中的可选字段, 没有可选字段时为 nil
+func optionalFields(fds []field) map[string]bool {
+	var opt map[string]bool
+	for _, fd := range fds {
+		if fd.Optional {
+			if opt == nil {
+				opt = map[string]bool{}
+			}
+			opt[fd.Name] = true
+		}
+	}
+	return opt
+}
+
 func (c *compactType) isEmpty() bool {
-	return c.vs.Len() == 0 && c.prim.Len() == 0 && c.rec == nil && c.fun == nil
+	return c.vs.Len() == 0 && c.prim.Len() == 0 && c.tup == nil && c.rec == nil && len(c.ext) == 0 && c.vnt == nil && c.ref == nil && c.fun == nil && len(c.ctor) == 0 && len(c.inter) == 0 && len(c.uni) == 0 && len(c.neg) == 0
 }
 
 func (c *compactType) String() string {
@@ -52,20 +116,52 @@ func (c *compactType) String() string {
 	for _, prim := range c.prim.Values() {
 		xs = append(xs, prim.Name)
 	}
+	if c.tup != nil {
+		xss := make([]string, len(c.tup))
+		for i, el := range c.tup {
+			xss[i] = el.String()
+		}
+		xs = append(xs, util.JoinStr(xss, ", ", "(", ")"))
+	}
 	if c.rec != nil {
 		if c.rec.Len() == 0 {
 			xs = append(xs, "{}")
 		} else {
 			xss := make([]string, c.rec.Len())
 			for i, name := range c.rec.Keys() {
-				xss[i] = fmt.Sprintf("%s: %s", name, c.rec.Get(name))
+				xss[i] = fmt.Sprintf("%s: %s", c.fieldName(name), c.rec.Get(name))
 			}
 			xs = append(xs, util.JoinStr(xss, ", ", "{", "}"))
 		}
 	}
+	for _, ext := range c.ext {
+		xs = append(xs, ext.stringify((*compactType).String))
+	}
+	if c.vnt != nil {
+		xss := make([]string, c.vnt.Len())
+		for i, name := range c.vnt.Keys() {
+			xss[i] = fmt.Sprintf("%s %s", name, c.vnt.Get(name))
+		}
+		xs = append(xs, util.JoinStr(xss, " | ", "[", "]"))
+	}
+	if c.ref != nil {
+		xs = append(xs, fmt.Sprintf("Ref[in %s, out %s]", c.ref.lhs, c.ref.rhs))
+	}
 	if c.fun != nil {
 		xs = append(xs, fmt.Sprintf("%s -> %s", c.fun.lhs, c.fun.rhs))
 	}
+	for _, ctor := range c.ctor {
+		xs = append(xs, ctor.stringify((*compactType).String))
+	}
+	for _, it := range c.inter {
+		xs = append(xs, it.String())
+	}
+	for _, u := range c.uni {
+		xs = append(xs, u.String())
+	}
+	for _, n := range c.neg {
+		xs = append(xs, n.String())
+	}
 	return util.JoinStr(xs, ", ", "‹", "›")
 }
 
@@ -99,18 +195,58 @@ func (c *compactType) hash() string {
 		}
 	}
 
+	if c.tup != nil {
+		xss := make([]string, len(c.tup))
+		for i, el := range c.tup {
+			xss[i] = el.hash()
+		}
+		xs = append(xs, util.JoinStr(xss, ", ", "(", ")"))
+	}
+
 	if c.rec != nil && c.rec.Len() != 0 {
 		xss := make([]string, c.rec.Len())
 		for i, name := range c.rec.Keys() {
-			xss[i] = fmt.Sprintf("%s: %s", name, c.rec.Get(name).hash())
+			xss[i] = fmt.Sprintf("%s: %s", c.fieldName(name), c.rec.Get(name).hash())
 		}
 		xs = append(xs, util.JoinStr(xss, ", ", "{", "}"))
 	}
 
+	for _, ext := range c.ext {
+		xs = append(xs, ext.stringify((*compactType).hash))
+	}
+
+	if c.vnt != nil {
+		xss := make([]string, c.vnt.Len())
+		for i, name := range c.vnt.Keys() {
+			xss[i] = fmt.Sprintf("%s %s", name, c.vnt.Get(name).hash())
+		}
+		xs = append(xs, util.JoinStr(xss, " | ", "[", "]"))
+	}
+
+	if c.ref != nil {
+		xs = append(xs, fmt.Sprintf("ref %s, %s", c.ref.lhs.hash(), c.ref.rhs.hash()))
+	}
+
 	if c.fun != nil {
 		xs = append(xs, fmt.Sprintf("%s -> %s", c.fun.lhs.hash(), c.fun.rhs.hash()))
 	}
 
+	for _, ctor := range c.ctor {
+		xs = append(xs, ctor.stringify((*compactType).hash))
+	}
+
+	for _, it := range c.inter {
+		xs = append(xs, it.hash())
+	}
+
+	for _, u := range c.uni {
+		xs = append(xs, u.hash())
+	}
+
+	for _, n := range c.neg {
+		xs = append(xs, n.hash())
+	}
+
 	c._hash = util.JoinStr(xs, ", ", "‹", "›")
 	return c._hash
 }
@@ -32,6 +32,29 @@ func (t *Typer) canonicalizeType(st SimpleType) *compactTypeScheme {
 				rec[fd.Name] = do0(fd.Type, pol)
 			}
 			cty.rec = rec.ToSorted()
+			cty.opt = optionalFields(ty.Fields)
+		case *Extension:
+			fields := nameCompactMap{}
+			for _, fd := range ty.Fields {
+				fields[fd.Name] = do0(fd.Type, pol)
+			}
+			cty.ext = []*compactExt{{do0(ty.Base, pol), fields.ToSorted(), ty.Removed}}
+		case *Ref:
+			cty.ref = &compactFun{do0(ty.Write, !pol), do0(ty.Read, pol)}
+		case *Constructor:
+			cty.ctor = []*compactCtor{t.compactCtor(ty, pol, do0)}
+		case *Variant:
+			vnt := nameCompactMap{}
+			for _, tag := range ty.Tags {
+				vnt[tag.Name] = do0(tag.Type, pol)
+			}
+			cty.vnt = vnt.ToSorted()
+		case *Intersection:
+			cty.inter = []*Intersection{ty}
+		case *Union:
+			cty.uni = []*Union{ty}
+		case *Negation:
+			cty.neg = []*Negation{ty}
 		case *Variable:
 			cty.vs = closeOver(unsortedVarSet{}, unsortedVarSet{ty.uid: ty}, pol).ToSorted(ASC)
 		default:
@@ -77,8 +100,17 @@ func (t *Typer) canonicalizeType(st SimpleType) *compactTypeScheme {
 			}
 		}
 		adapted := &compactType{
-			vs:   res.vs,
-			prim: res.prim,
+			vs:    res.vs,
+			prim:  res.prim,
+			inter: res.inter,
+			uni:   res.uni,
+			neg:   res.neg,
+		}
+		if res.tup != nil {
+			adapted.tup = make([]*compactType, len(res.tup))
+			for i, el := range res.tup {
+				adapted.tup[i] = do1(el, pol, inProcess)
+			}
 		}
 		if res.rec != nil {
 			m := nameCompactMap{}
@@ -86,6 +118,27 @@ func (t *Typer) canonicalizeType(st SimpleType) *compactTypeScheme {
 				m[name] = do1(res.rec.Get(name), pol, inProcess)
 			}
 			adapted.rec = m.ToSorted()
+			adapted.opt = res.opt
+		}
+		for _, ext := range res.ext {
+			m := nameCompactMap{}
+			for _, name := range ext.fields.Keys() {
+				m[name] = do1(ext.fields.Get(name), pol, inProcess)
+			}
+			adapted.ext = append(adapted.ext, &compactExt{do1(ext.base, pol, inProcess), m.ToSorted(), ext.removed})
+		}
+		if res.vnt != nil {
+			m := nameCompactMap{}
+			for _, name := range res.vnt.Keys() {
+				m[name] = do1(res.vnt.Get(name), pol, inProcess)
+			}
+			adapted.vnt = m.ToSorted()
+		}
+		if res.ref != nil {
+			adapted.ref = &compactFun{
+				lhs: do1(res.ref.lhs, !pol, inProcess),
+				rhs: do1(res.ref.rhs, pol, inProcess),
+			}
 		}
 		if res.fun != nil {
 			adapted.fun = &compactFun{
@@ -94,6 +147,17 @@ func (t *Typer) canonicalizeType(st SimpleType) *compactTypeScheme {
 			}
 		}
 
+		for _, ctor := range res.ctor {
+			args := make([]*compactFun, len(ctor.args))
+			for i, arg := range ctor.args {
+				args[i] = &compactFun{
+					lhs: do1(arg.lhs, !pol, inProcess),
+					rhs: do1(arg.rhs, pol, inProcess),
+				}
+			}
+			adapted.ctor = append(adapted.ctor, &compactCtor{ctor.name, args})
+		}
+
 		r := recursive.Get(pc)
 		if r == nil {
 			return adapted
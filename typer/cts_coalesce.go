@@ -9,12 +9,20 @@ import (
 // 将一个 compactTypeScheme coalesce 成一个 types.Type，
 // 同时执行 hash-consing，尽可能将递归类型变得紧凑
 func (t *Typer) coalesceCompactType(cty *compactTypeScheme) types.Type {
+	return t.coalesceCompactType0(cty, true)
+}
+
+// hashConsing 为 false 时只记录递归变量, 只有经过 recVars 的环才会生成递归类型
+func (t *Typer) coalesceCompactType0(cty *compactTypeScheme, hashConsing bool) types.Type {
 	var do func(compactTypeOrVariable, bool, polarCompactMap) types.Type
 	do = func(ctv compactTypeOrVariable, pol bool, inProcess polarCompactMap) types.Type {
+		_, isVar := ctv.(*Variable)
+		consing := isVar || hashConsing
+
 		pc := newPolarCompact(ctv, pol)
 
 		thunk := inProcess.Get(pc)
-		if thunk != nil {
+		if consing && thunk != nil {
 			res := thunk.(typeVarThunk)()
 			log("[coalesceCompactType.REC][%t] %v -> %v", pol, ctv, res)
 			return res
@@ -39,8 +47,10 @@ func (t *Typer) coalesceCompactType(cty *compactTypeScheme) types.Type {
 			}
 		}()
 
-		inProcess.Put(pc, typeVarThunk(v))
-		defer inProcess.Del(pc)
+		if consing {
+			inProcess.Put(pc, typeVarThunk(v))
+			defer inProcess.Del(pc)
+		}
 
 		var res types.Type
 		switch ty := ctv.(type) {
@@ -59,19 +69,66 @@ func (t *Typer) coalesceCompactType(cty *compactTypeScheme) types.Type {
 			for _, prim := range ty.prim.Values() {
 				lst = append(lst, types.Prim(prim.Name))
 			}
+			if ty.tup != nil {
+				xs := make([]types.Type, len(ty.tup))
+				for i, el := range ty.tup {
+					xs[i] = do(el, pol, inProcess)
+				}
+				lst = append(lst, types.Tuple(xs))
+			}
 			if ty.rec != nil {
 				xs := make([]types.Field, ty.rec.Len())
 				for i, name := range ty.rec.Keys() {
 					fty := do(ty.rec.Get(name), pol, inProcess)
-					xs[i] = types.Field{Name: name, Type: fty}
+					xs[i] = types.Field{Name: name, Type: fty, Optional: ty.opt[name]}
 				}
 				rt := types.Record(xs)
 				lst = append(lst, rt)
 			}
+			for _, ext := range ty.ext {
+				xs := make([]types.Field, ext.fields.Len())
+				for i, name := range ext.fields.Keys() {
+					xs[i] = types.Field{Name: name, Type: do(ext.fields.Get(name), pol, inProcess)}
+				}
+				lst = append(lst, types.Extension(do(ext.base, pol, inProcess), xs, ext.removed))
+			}
+			if ty.vnt != nil {
+				xs := make([]types.Field, ty.vnt.Len())
+				for i, name := range ty.vnt.Keys() {
+					xs[i] = types.Field{Name: name, Type: do(ty.vnt.Get(name), pol, inProcess)}
+				}
+				lst = append(lst, types.Variant(xs))
+			}
+			if ty.ref != nil {
+				rt := types.Ref(do(ty.ref.lhs, !pol, inProcess), do(ty.ref.rhs, pol, inProcess))
+				lst = append(lst, rt)
+			}
 			if ty.fun != nil {
 				ft := types.Func(do(ty.fun.lhs, !pol, inProcess), do(ty.fun.rhs, pol, inProcess))
 				lst = append(lst, ft)
 			}
+			for _, ctor := range ty.ctor {
+				vs := t.variances(ctor.name, len(ctor.args))
+				xs := make([]types.CtorArg, len(ctor.args))
+				for i, arg := range ctor.args {
+					if vs[i]&contravariant != 0 {
+						xs[i].In = do(arg.lhs, !pol, inProcess)
+					}
+					if vs[i]&covariant != 0 {
+						xs[i].Out = do(arg.rhs, pol, inProcess)
+					}
+				}
+				lst = append(lst, types.Ctor(ctor.name, xs))
+			}
+			for _, it := range ty.inter {
+				lst = append(lst, t.coalesceType(it))
+			}
+			for _, u := range ty.uni {
+				lst = append(lst, t.coalesceType(u))
+			}
+			for _, n := range ty.neg {
+				lst = append(lst, t.coalesceType(n))
+			}
 			res = mergeTypes(lst, pol)
 		}
 
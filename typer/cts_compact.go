@@ -43,6 +43,67 @@ func (t *Typer) compactType(st SimpleType) *compactTypeScheme {
 			}
 			return &compactType{
 				rec:  rec.ToSorted(),
+				opt:  optionalFields(ty.Fields),
+				vs:   emptyVarSet(),
+				prim: emptyPrimSet(),
+			}
+		case *Extension:
+			fields := nameCompactMap{}
+			for _, fd := range ty.Fields {
+				fields[fd.Name] = do(fd.Type, pol, varSet{}, inProcess)
+			}
+			return &compactType{
+				ext: []*compactExt{{
+					base:    do(ty.Base, pol, varSet{}, inProcess),
+					fields:  fields.ToSorted(),
+					removed: ty.Removed,
+				}},
+				vs:   emptyVarSet(),
+				prim: emptyPrimSet(),
+			}
+		case *Ref:
+			return &compactType{
+				ref: &compactFun{
+					lhs: do(ty.Write, !pol, varSet{}, inProcess),
+					rhs: do(ty.Read, pol, varSet{}, inProcess),
+				},
+				vs:   emptyVarSet(),
+				prim: emptyPrimSet(),
+			}
+		case *Constructor:
+			ctor := t.compactCtor(ty, pol, func(st SimpleType, pol bool) *compactType {
+				return do(st, pol, varSet{}, inProcess)
+			})
+			return &compactType{
+				ctor: []*compactCtor{ctor},
+				vs:   emptyVarSet(),
+				prim: emptyPrimSet(),
+			}
+		case *Variant:
+			vnt := nameCompactMap{}
+			for _, tag := range ty.Tags {
+				vnt[tag.Name] = do(tag.Type, pol, varSet{}, inProcess)
+			}
+			return &compactType{
+				vnt:  vnt.ToSorted(),
+				vs:   emptyVarSet(),
+				prim: emptyPrimSet(),
+			}
+		case *Intersection:
+			return &compactType{
+				inter: []*Intersection{ty},
+				vs:    emptyVarSet(),
+				prim:  emptyPrimSet(),
+			}
+		case *Union:
+			return &compactType{
+				uni:  []*Union{ty},
+				vs:   emptyVarSet(),
+				prim: emptyPrimSet(),
+			}
+		case *Negation:
+			return &compactType{
+				neg:  []*Negation{ty},
 				vs:   emptyVarSet(),
 				prim: emptyPrimSet(),
 			}
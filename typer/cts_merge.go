@@ -17,50 +17,251 @@ func merge(lhs, rhs *compactType, pol bool) *compactType {
 		prims.Add(prim)
 	}
 
+	rec, opt := mergeRec(lhs, rhs, pol)
 	return &compactType{
 		vs:   vars.ToSorted(ASC),
 		prim: prims.ToSorted(),
-		rec:  mergeRec(lhs.rec, rhs.rec, pol),
+		tup:  mergeTup(lhs.tup, rhs.tup, pol),
+		rec:  rec,
+		opt:  opt,
+		ext:  mergeExt(lhs.ext, rhs.ext, pol),
+		vnt:  mergeVnt(lhs.vnt, rhs.vnt, pol),
+		ref:  mergeFun(lhs.ref, rhs.ref, pol), // 与函数相同, 写入逆变读取协变
 		fun:  mergeFun(lhs.fun, rhs.fun, pol),
+		ctor: mergeCtor(lhs.ctor, rhs.ctor, pol),
+
+		inter: mergeInter(lhs.inter, rhs.inter),
+		uni:   mergeUnion(lhs.uni, rhs.uni),
+		neg:   mergeNeg(lhs.neg, rhs.neg),
 	}
 }
 
-func mergeRec(lhs, rhs *sortedNameCompactMap, pol bool) *sortedNameCompactMap {
+// mergeInter 交集无法与其他类型合并, 去重保留
+func mergeInter(lhs, rhs []*Intersection) []*Intersection {
+	if len(lhs) == 0 {
+		return rhs
+	}
+	res := lhs
+	for _, r := range rhs {
+		dup := false
+		for _, l := range lhs {
+			dup = dup || l.hash() == r.hash()
+		}
+		if !dup {
+			res = append(res[:len(res):len(res)], r)
+		}
+	}
+	return res
+}
+
+// mergeTup 与 mergeRec 相同, 元素视为按位置命名的字段:
+// 正极 (并集) 保留公共的前缀, 负极 (交集) 保留较长的 tuple
+func mergeTup(lhs, rhs []*compactType, pol bool) []*compactType {
 	switch {
 	case lhs != nil && rhs != nil:
+		if len(lhs) > len(rhs) == pol {
+			lhs, rhs = rhs, lhs
+		}
+		// 正极 lhs 较短, 负极 lhs 较长
+		tup := make([]*compactType, len(lhs))
+		for i, lv := range lhs {
+			if i < len(rhs) {
+				tup[i] = merge(lv, rhs[i], pol)
+			} else {
+				tup[i] = lv
+			}
+		}
+		return tup
+	case lhs != nil && rhs == nil:
+		return lhs
+	default:
+		return rhs
+	}
+}
+
+// mergeRec 返回合并后的字段与可选字段
+// 正极保留公共的字段, 任意一侧可选则可选, e.g. {a: int} ∨ {a?: bool} = {a?: int ∨ bool}
+// 负极保留所有字段, 两侧都有的字段都可选才可选, e.g. {a?: int} ∧ {a: bool} = {a: int ∧ bool}
+func mergeRec(lhs, rhs *compactType, pol bool) (*sortedNameCompactMap, map[string]bool) {
+	switch {
+	case lhs.rec != nil && rhs.rec != nil:
 		rec := nameCompactMap{}
+		var opt map[string]bool
+		optional := func(name string) {
+			if opt == nil {
+				opt = map[string]bool{}
+			}
+			opt[name] = true
+		}
 		if pol {
 			// 交集
-			for _, name := range lhs.Keys() {
-				lv := lhs.Get(name)
-				rv := rhs.Get(name)
+			for _, name := range lhs.rec.Keys() {
+				lv := lhs.rec.Get(name)
+				rv := rhs.rec.Get(name)
 				if rv != nil {
 					rec[name] = merge(lv, rv, pol)
+					if lhs.opt[name] || rhs.opt[name] {
+						optional(name)
+					}
 				}
 			}
 		} else {
 			// 并集
-			for _, name := range lhs.Keys() {
-				rec[name] = lhs.Get(name)
+			for _, name := range lhs.rec.Keys() {
+				rec[name] = lhs.rec.Get(name)
+				if lhs.opt[name] && (rhs.rec.Get(name) == nil || rhs.opt[name]) {
+					optional(name)
+				}
 			}
-			for _, name := range rhs.Keys() {
+			for _, name := range rhs.rec.Keys() {
 				lv := rec[name]
-				rv := rhs.Get(name)
+				rv := rhs.rec.Get(name)
 				if lv == nil {
 					rec[name] = rv
+					if rhs.opt[name] {
+						optional(name)
+					}
 				} else {
 					rec[name] = merge(lv, rv, pol)
 				}
 			}
 		}
-		return rec.ToSorted()
-	case lhs != nil && rhs == nil:
+		return rec.ToSorted(), opt
+	case lhs.rec != nil && rhs.rec == nil:
+		return lhs.rec, lhs.opt
+	case lhs.rec == nil && rhs.rec != nil:
+		return rhs.rec, rhs.opt
+	default:
+		return nil, nil
+	}
+}
+
+// mergeExt 扩展只出现在正极, 形状相同的扩展逐字段合并, e.g. {'a with x: int} ∨ {'b with x: bool} = {'a ∨ 'b with x: int ∨ bool}
+// 形状不同的扩展保留为并集
+func mergeExt(lhs, rhs []*compactExt, pol bool) []*compactExt {
+	if len(lhs) == 0 {
+		return rhs
+	}
+	if len(rhs) == 0 {
 		return lhs
-	case lhs == nil && rhs != nil:
+	}
+	res := make([]*compactExt, len(lhs), len(lhs)+len(rhs))
+	copy(res, lhs)
+outer:
+	for _, r := range rhs {
+		for i, l := range res {
+			if l.shape() == r.shape() {
+				fields := nameCompactMap{}
+				for _, name := range l.fields.Keys() {
+					fields[name] = merge(l.fields.Get(name), r.fields.Get(name), pol)
+				}
+				res[i] = &compactExt{merge(l.base, r.base, pol), fields.ToSorted(), l.removed}
+				continue outer
+			}
+		}
+		res = append(res, r)
+	}
+	return res
+}
+
+// mergeUnion 与 mergeInter 相同, 去重保留
+func mergeUnion(lhs, rhs []*Union) []*Union {
+	if len(lhs) == 0 {
 		return rhs
-	default:
-		return nil
 	}
+	res := lhs
+	for _, r := range rhs {
+		dup := false
+		for _, l := range lhs {
+			dup = dup || l.hash() == r.hash()
+		}
+		if !dup {
+			res = append(res[:len(res):len(res)], r)
+		}
+	}
+	return res
+}
+
+func mergeNeg(lhs, rhs []*Negation) []*Negation {
+	if len(lhs) == 0 {
+		return rhs
+	}
+	res := lhs
+	for _, r := range rhs {
+		dup := false
+		for _, l := range lhs {
+			dup = dup || l.hash() == r.hash()
+		}
+		if !dup {
+			res = append(res[:len(res):len(res)], r)
+		}
+	}
+	return res
+}
+
+// mergeVnt 与 mergeRec 对偶: 正极标签取并集, 负极标签取交集
+func mergeVnt(lhs, rhs *sortedNameCompactMap, pol bool) *sortedNameCompactMap {
+	if lhs != nil && rhs != nil {
+		vnt := nameCompactMap{}
+		if pol {
+			for _, name := range lhs.Keys() {
+				vnt[name] = lhs.Get(name)
+			}
+			for _, name := range rhs.Keys() {
+				lv := vnt[name]
+				rv := rhs.Get(name)
+				if lv == nil {
+					vnt[name] = rv
+				} else {
+					vnt[name] = merge(lv, rv, pol)
+				}
+			}
+		} else {
+			for _, name := range lhs.Keys() {
+				if rv := rhs.Get(name); rv != nil {
+					vnt[name] = merge(lhs.Get(name), rv, pol)
+				}
+			}
+		}
+		return vnt.ToSorted()
+	}
+	if lhs != nil {
+		return lhs
+	}
+	return rhs
+}
+
+// mergeCtor 同名构造器逐参数合并, 与函数相同, 逆变分量取相反的极性
+func mergeCtor(lhs, rhs []*compactCtor, pol bool) []*compactCtor {
+	if len(lhs) == 0 {
+		return rhs
+	}
+	if len(rhs) == 0 {
+		return lhs
+	}
+	res := make([]*compactCtor, 0, len(lhs)+len(rhs))
+	i, j := 0, 0
+	for i < len(lhs) && j < len(rhs) {
+		l, r := lhs[i], rhs[j]
+		switch {
+		case l.name < r.name:
+			res = append(res, l)
+			i++
+		case l.name > r.name:
+			res = append(res, r)
+			j++
+		default:
+			args := make([]*compactFun, len(l.args))
+			for k, arg := range l.args {
+				args[k] = mergeFun(arg, r.args[k], pol)
+			}
+			res = append(res, &compactCtor{l.name, args})
+			i++
+			j++
+		}
+	}
+	res = append(res, lhs[i:]...)
+	return append(res, rhs[j:]...)
 }
 
 func mergeFun(lhs, rhs *compactFun, pol bool) *compactFun {
@@ -77,6 +77,14 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 			}
 		}
 
+		var tupThunks []compactTypeThunk
+		if ty.tup != nil {
+			tupThunks = make([]compactTypeThunk, len(ty.tup))
+			for i, el := range ty.tup {
+				tupThunks[i] = do(el, pol)
+			}
+		}
+
 		var recThunk *sortedNameCompactThunkMap
 		if ty.rec != nil {
 			rec := nameCompactThunkMap{}
@@ -86,6 +94,33 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 			recThunk = rec.ToSorted()
 		}
 
+		extBaseThunks := make([]compactTypeThunk, len(ty.ext))
+		extThunks := make([]*sortedNameCompactThunkMap, len(ty.ext))
+		for i, ext := range ty.ext {
+			extBaseThunks[i] = do(ext.base, pol)
+			m := nameCompactThunkMap{}
+			for _, name := range ext.fields.Keys() {
+				m[name] = do(ext.fields.Get(name), pol)
+			}
+			extThunks[i] = m.ToSorted()
+		}
+
+		var vntThunk *sortedNameCompactThunkMap
+		if ty.vnt != nil {
+			vnt := nameCompactThunkMap{}
+			for _, name := range ty.vnt.Keys() {
+				vnt[name] = do(ty.vnt.Get(name), pol)
+			}
+			vntThunk = vnt.ToSorted()
+		}
+
+		var writeThunk compactTypeThunk
+		var readThunk compactTypeThunk
+		if ty.ref != nil {
+			writeThunk = do(ty.ref.lhs, !pol)
+			readThunk = do(ty.ref.rhs, pol)
+		}
+
 		var lhsThunk compactTypeThunk
 		var rhsThunk compactTypeThunk
 		if ty.fun != nil {
@@ -93,6 +128,13 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 			rhsThunk = do(ty.fun.rhs, pol)
 		}
 
+		ctorThunks := make([][]*compactFunThunk, len(ty.ctor))
+		for i, ctor := range ty.ctor {
+			for _, arg := range ctor.args {
+				ctorThunks[i] = append(ctorThunks[i], &compactFunThunk{do(arg.lhs, !pol), do(arg.rhs, pol)})
+			}
+		}
+
 		return func() *compactType {
 			newVars := unsortedVarSet{}
 			for _, tv := range ty.vs.Values() {
@@ -104,6 +146,14 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 				}
 			}
 
+			var tup []*compactType
+			if tupThunks != nil {
+				tup = make([]*compactType, len(tupThunks))
+				for i, thunk := range tupThunks {
+					tup[i] = thunk()
+				}
+			}
+
 			var rec *sortedNameCompactMap
 			if recThunk != nil {
 				m := nameCompactMap{}
@@ -113,6 +163,32 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 				rec = m.ToSorted()
 			}
 
+			var ext []*compactExt
+			for i, thunk := range extThunks {
+				m := nameCompactMap{}
+				for _, name := range thunk.Keys() {
+					m[name] = thunk.Get(name)()
+				}
+				ext = append(ext, &compactExt{extBaseThunks[i](), m.ToSorted(), ty.ext[i].removed})
+			}
+
+			var vnt *sortedNameCompactMap
+			if vntThunk != nil {
+				m := nameCompactMap{}
+				for _, name := range vntThunk.Keys() {
+					m[name] = vntThunk.Get(name)()
+				}
+				vnt = m.ToSorted()
+			}
+
+			var ref *compactFun
+			if writeThunk != nil {
+				ref = &compactFun{
+					lhs: writeThunk(),
+					rhs: readThunk(),
+				}
+			}
+
 			var fun *compactFun
 			if lhsThunk != nil {
 				fun = &compactFun{
@@ -121,11 +197,31 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 				}
 			}
 
+			var ctor []*compactCtor
+			for i, thunks := range ctorThunks {
+				args := make([]*compactFun, len(thunks))
+				for j, thunk := range thunks {
+					args[j] = &compactFun{thunk.lhs(), thunk.rhs()}
+				}
+				ctor = append(ctor, &compactCtor{ty.ctor[i].name, args})
+			}
+
+			prim, neg := t.normalizeNegs(t.widenPrims(ty.prim, pol), ty.neg, pol)
 			return &compactType{
 				vs:   newVars.ToSorted(ASC),
-				prim: ty.prim,
+				prim: prim,
+				tup:  tup,
 				rec:  rec,
+				opt:  ty.opt,
+				ext:  ext,
+				vnt:  vnt,
+				ref:  ref,
 				fun:  fun,
+				ctor: ctor,
+
+				inter: ty.inter,
+				uni:   ty.uni,
+				neg:   neg,
 			}
 		}
 	}
@@ -144,6 +240,9 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 			rhs := coOccurs.Get(newPolarVar(tv, false))
 			if (lhs != nil && rhs == nil) || (lhs == nil && rhs != nil) {
 				log("[simplifyType.!] %v", tv)
+				if t.tracer != nil {
+					t.trace(&Event{Kind: EvRemove, Lhs: tv.String(), Polarity: polarity(lhs != nil), Reason: "occurs in one polarity only"})
+				}
 				varSubst.Put(tv, nil)
 			} else if lhs == nil && rhs == nil {
 				panic("assert")
@@ -193,6 +292,9 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 					}
 
 					log("[simplifyType.U] %v = %v", w, v)
+					if t.tracer != nil {
+						t.trace(&Event{Kind: EvSubst, Polarity: polarity(pol), Lhs: w.String(), Rhs: v.String()})
+					}
 					varSubst.Put(w, v) // unify w into v
 
 					// 由于合并了 w 和 v, 如果它们是递归的，我们需要合并它们的边界，否则就合并 v 和 w 的其他共现的极性(!pol)。
@@ -228,6 +330,9 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 					vOccurs1 := coOccurs.Get(newPolarVar(v, !pol))
 					if vOccurs1 != nil {
 						if vOccurs1.Contains(w) {
+							if t.tracer != nil {
+								t.trace(&Event{Kind: EvRemove, Lhs: v.String(), Reason: "co-occurs with " + w.Name + " in both polarities"})
+							}
 							varSubst.Put(v, nil)
 						}
 					}
@@ -248,3 +353,29 @@ func (t *Typer) simplifyType(cty *compactTypeScheme) *compactTypeScheme {
 	}
 	return &compactTypeScheme{term, varCmtMap.ToSorted()}
 }
+
+// widenPrims 按基本类型的子类型关系合并共现的基本类型 (包括单例类型)
+// 正极 (并集) 保留父类型, e.g. "GET" ∨ string = string, nat ∨ int = int
+// 负极 (交集) 保留子类型, e.g. "GET" ∧ string = "GET", nat ∧ int = nat
+func (t *Typer) widenPrims(prims *sortedPrimSet, pol bool) *sortedPrimSet {
+	res := unsortedPrimSet{}
+	changed := false
+	for _, p := range prims.Values() {
+		redundant := false
+		for _, q := range prims.Values() {
+			if p != q && (pol && t.isSubPrim(p, q) || !pol && t.isSubPrim(q, p)) {
+				redundant = true
+				break
+			}
+		}
+		if redundant {
+			changed = true
+		} else {
+			res.Add(p)
+		}
+	}
+	if !changed {
+		return prims
+	}
+	return res.ToSorted()
+}
@@ -0,0 +1,123 @@
+package typer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goghcrow/simple-sub/terms"
+	"github.com/goghcrow/simple-sub/types"
+)
+
+// Pipeline 分阶段的类型推导, 暴露每个阶段的中间表示, 方便工具和 golden test 针对单个 pass 断言
+//
+// inferred   = inferType(term)
+// compacted  = canonicalizeType(inferred)
+// simplified = simplifyType(compacted)
+// coalesced  = coalesceCompactType(simplified)
+type Pipeline struct {
+	typer *Typer
+	ctx   *Ctx
+}
+
+// Stages 各阶段结果, 被 Simplification 跳过的阶段为 nil
+type Stages struct {
+	Inferred   SimpleType
+	Compacted  *CompactTypeScheme
+	Simplified *CompactTypeScheme
+	Coalesced  types.Type
+}
+
+// CompactTypeScheme 化简阶段使用的 compact 表示, 只读
+type CompactTypeScheme struct {
+	cty *compactTypeScheme
+}
+
+func NewPipeline(t *Typer, ctx *Ctx) *Pipeline {
+	return &Pipeline{typer: t, ctx: ctx}
+}
+
+// Term 推导单个 term 的各阶段
+func (p *Pipeline) Term(term terms.Term) (res *Stages, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = TypeErrorOf(r)
+		}
+	}()
+	// 不使用之前程序声明的类型与运行时转换
+	p.typer.typeDecls = p.typer.base
+	p.typer.casts = nil
+	st := p.typer.inferType(term, p.ctx)
+	return p.typer.stages(st), nil
+}
+
+// Program 按顺序推导所有顶层定义的各阶段, 不修改 Pipeline 的 ctx
+func (p *Pipeline) Program(pgrm *terms.Program) (res []*Stages, err error) {
+	tyv, err := p.typer.inferTypes(pgrm, p.ctx.clone())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = TypeErrorOf(r)
+		}
+	}()
+	res = make([]*Stages, len(tyv))
+	for i, poly := range tyv {
+		res[i] = p.typer.stages(poly.instantiate(p.typer, 0))
+	}
+	return
+}
+
+// stages 根据 Typer 的 Simplification 选择执行的阶段, 跳过的阶段为 nil
+func (t *Typer) stages(st SimpleType) (res *Stages) {
+	if t.aliasFolding {
+		defer func() { res.Coalesced = t.foldAliases(res.Coalesced) }()
+	}
+	opt := t.simplification
+	if !opt.Canonicalize {
+		return &Stages{Inferred: st, Coalesced: t.coalesceType(st)}
+	}
+	cty := t.canonicalizeType(st)
+	res = &Stages{Inferred: st, Compacted: &CompactTypeScheme{cty}}
+	if opt.CoOccurrence {
+		cty = t.simplifyType(cty)
+		res.Simplified = &CompactTypeScheme{cty}
+	}
+	res.Coalesced = t.coalesceCompactType0(cty, opt.HashConsing)
+	return res
+}
+
+// Where 推导结果中类型变量的边界
+func (s *Stages) Where() string { return ShowBounds(s.Inferred) }
+
+func (s *Stages) String() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("inferred: %s\n", s.Inferred))
+	if where := s.Where(); where != "" {
+		b.WriteString(fmt.Sprintf("where: %s\n", where))
+	}
+	if s.Compacted != nil {
+		b.WriteString(fmt.Sprintf("compacted: %s\n", s.Compacted))
+	}
+	if s.Simplified != nil {
+		b.WriteString(fmt.Sprintf("simplified: %s\n", s.Simplified))
+	}
+	b.WriteString(fmt.Sprintf("coalesced: %s\n", s.Coalesced.Show()))
+	return b.String()
+}
+
+// String 递归变量按 uid 排序, 输出稳定
+func (c *CompactTypeScheme) String() string {
+	keys := c.cty.recVars.Keys()
+	if len(keys) == 0 {
+		return c.cty.term.String()
+	}
+	xs := make([]string, len(keys))
+	for i, v := range keys {
+		xs[i] = fmt.Sprintf("%s = %s", v, c.cty.recVars.Get(v))
+	}
+	return c.cty.term.String() + " where " + strings.Join(xs, ", ")
+}
+
+// Dot 导出为 graphviz dot, 参见 ShowCanonicalDot
+func (c *CompactTypeScheme) Dot() string { return c.cty.dot() }
@@ -0,0 +1,104 @@
+package typer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Tracer 结构化跟踪类型推导过程, 通过 WithTracer 注入 Typer
+// 相比 DBG 开关, 不需要重新编译, 并且可以输出机器可读的事件流
+type Tracer interface {
+	Trace(e *Event)
+}
+
+type EventKind string
+
+const (
+	EvFresh       EventKind = "fresh"       // 创建类型变量
+	EvConstrain   EventKind = "constrain"   // 约束 Lhs <: Rhs
+	EvBound       EventKind = "bound"       // 变量 Lhs 加入边界 Rhs, Polarity + 下界 / - 上界
+	EvExtrude     EventKind = "extrude"     // 修正 level, Lhs extrude 为 Rhs
+	EvInstantiate EventKind = "instantiate" // 实例化多态类型 Lhs 为 Rhs
+	EvMerge       EventKind = "merge"       // 合并环上等价变量 Lhs 为代表变量 Rhs
+	EvRemove      EventKind = "simplify.remove"
+	EvSubst       EventKind = "simplify.subst" // 化简时将变量 Lhs 替换为 Rhs
+)
+
+type Event struct {
+	Kind     EventKind `json:"kind"`
+	Depth    int       `json:"depth"` // constrain 递归深度
+	Level    int       `json:"level"`
+	Polarity string    `json:"polarity,omitempty"`
+	Lhs      string    `json:"lhs,omitempty"`
+	Rhs      string    `json:"rhs,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+func WithTracer(tr Tracer) Option {
+	return func(t *Typer) { t.tracer = tr }
+}
+
+func (t *Typer) trace(e *Event) {
+	if t.tracer != nil {
+		e.Depth = t.depth
+		t.tracer.Trace(e)
+	}
+}
+
+func polarity(pol bool) string {
+	if pol {
+		return "+"
+	}
+	return "-"
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewJSONTracer 每个事件输出一行 json
+func NewJSONTracer(w io.Writer) Tracer { return &jsonTracer{json.NewEncoder(w)} }
+
+type jsonTracer struct {
+	enc *json.Encoder
+}
+
+func (j *jsonTracer) Trace(e *Event) { _ = j.enc.Encode(e) }
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewIndentTracer 按 constrain 深度缩进的可读输出
+func NewIndentTracer(w io.Writer) Tracer { return &indentTracer{w} }
+
+type indentTracer struct {
+	w io.Writer
+}
+
+func (i *indentTracer) Trace(e *Event) {
+	var msg string
+	switch e.Kind {
+	case EvFresh:
+		msg = fmt.Sprintf("fresh %s", e.Lhs)
+	case EvConstrain:
+		msg = fmt.Sprintf("%s <: %s", e.Lhs, e.Rhs)
+	case EvBound:
+		if e.Polarity == "+" {
+			msg = fmt.Sprintf("bound %s :> %s", e.Lhs, e.Rhs)
+		} else {
+			msg = fmt.Sprintf("bound %s <: %s", e.Lhs, e.Rhs)
+		}
+	case EvExtrude:
+		msg = fmt.Sprintf("extrude[%s] %s to level %d => %s", e.Polarity, e.Lhs, e.Level, e.Rhs)
+	case EvInstantiate:
+		msg = fmt.Sprintf("instantiate %s at level %d => %s", e.Lhs, e.Level, e.Rhs)
+	case EvMerge:
+		msg = fmt.Sprintf("merge %s => %s", e.Lhs, e.Rhs)
+	case EvRemove:
+		msg = fmt.Sprintf("simplify: remove %s (%s)", e.Lhs, e.Reason)
+	case EvSubst:
+		msg = fmt.Sprintf("simplify: %s := %s (%s)", e.Lhs, e.Rhs, e.Polarity)
+	default:
+		msg = fmt.Sprintf("%s %s %s", e.Kind, e.Lhs, e.Rhs)
+	}
+	_, _ = fmt.Fprintf(i.w, "%s%s\n", strings.Repeat("  ", e.Depth), msg)
+}
@@ -47,6 +47,8 @@ type (
 	}
 	Primitive struct {
 		Name string
+		// 单例类型 (字面量类型) 的基类型, e.g. "GET" <: string, 非单例类型为 nil
+		Base *Primitive
 	}
 	Tuple struct {
 		Elms []SimpleType
@@ -54,17 +56,91 @@ type (
 		_level int
 		_hash  string
 	}
+	// field 记录的字段或标签联合的标签, 只有记录的字段可以是可选的
 	field struct {
-		Name string
-		Type SimpleType
+		Name     string
+		Type     SimpleType
+		Optional bool
 	}
+	// Record 记录, 可选字段 {a?: T} 可以缺失, 存在时类型为 T
+	// 宽度子类型会遗忘字段, e.g. {a: bool} <: {}, 所以只有 closed 的记录缺少字段时确实没有该字段:
+	//
+	//	{} <: {a?: int}          closed, 来自记录字面量
+	//	({a: true} : {}) <: {a?: int}   不是 closed, 缺少的字段按 ⊤ 约束, ⊤ <: int 不成立
+	//
+	// 类型标注、类声明的字段等都不是 closed
 	Record struct {
 		Fields []field
+		closed bool
 
 		_level int
 		_hash  string
 		_map   map[string]SimpleType
 	}
+	// Extension 记录扩展 {Base with Fields} 与删除 Base \ Removed
+	// 只作为下界出现在正极, 约束到记录时按字段分解:
+	// Fields 中的字段直接约束, Removed 中的字段缺失, 其余字段约束到 Base
+	Extension struct {
+		Base    SimpleType
+		Fields  []field
+		Removed []string // 有序, 与 Fields 不相交
+
+		_level int
+		_hash  string
+		_map   map[string]SimpleType
+	}
+	// Variant 带标签的开放联合, 每个标签携带一个 payload, 无参数标签的 payload 为空记录
+	// 与 Record 对偶: 标签少的是子类型, e.g. [Some int] <: [Some int | None]
+	Variant struct {
+		Tags []field
+
+		_level int
+		_hash  string
+		_map   map[string]SimpleType
+	}
+	// Ref 引用类型 Ref[in Write, out Read]
+	// 写入逆变, 读取协变, 由 ref 创建时两者是同一个类型变量, 即不变
+	Ref struct {
+		Write SimpleType
+		Read  SimpleType
+
+		_level int
+		_hash  string
+	}
+	// Constructor 用户声明的参数化类型构造器 F[A1, ..., An]
+	// 与 Ref 相同, 每个参数保存逆变分量 In 与协变分量 Out, 按参数的型变使用, 参见 typer_ctors.go
+	Constructor struct {
+		Name string
+		Args []ctorArg
+
+		_level int
+		_hash  string
+	}
+	ctorArg struct {
+		In  SimpleType
+		Out SimpleType
+	}
+	// Intersection 重载, 只由 builtins 声明, 成员是不含类型变量的封闭类型
+	// e.g. add: (int -> int -> int) ∧ (float -> float -> float), 参见 ts_overload.go
+	Intersection struct {
+		Types []SimpleType
+
+		_hash string
+	}
+	// Union 类型标注中的并集, 与 Intersection 相同, 成员是不含类型变量的封闭类型
+	// e.g. {method: "GET" ∨ "POST"}, 参见 ts_union.go
+	Union struct {
+		Types []SimpleType
+
+		_hash string
+	}
+	// Negation 否定类型 ~Neg, 与 Intersection 相同, 只否定不含类型变量的封闭类型
+	// e.g. nonZero: int -> int ∧ ~0, 参见 ts_negation.go
+	Negation struct {
+		Neg SimpleType
+
+		_hash string
+	}
 	VariableState struct {
 		LowerBounds []SimpleType
 		UpperBounds []SimpleType
@@ -78,6 +154,7 @@ type (
 
 		_level   int
 		_typeVar *types.TypeVariable
+		_link    *Variable // 合并后指向等价类的代表变量, 参见 ts_mergevars.go
 	}
 )
 
@@ -100,6 +177,45 @@ func (r *Record) fieldMap() map[string]SimpleType {
 	return r._map
 }
 
+// optional 字段是否可选, 不存在的字段返回 false
+func (r *Record) optional(name string) bool {
+	for _, fd := range r.Fields {
+		if fd.Name == name {
+			return fd.Optional
+		}
+	}
+	return false
+}
+
+func (e *Extension) fieldMap() map[string]SimpleType {
+	if e._map == nil {
+		e._map = make(map[string]SimpleType)
+		for _, fd := range e.Fields {
+			e._map[fd.Name] = fd.Type
+		}
+	}
+	return e._map
+}
+
+func (e *Extension) removed(name string) bool {
+	for _, it := range e.Removed {
+		if it == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Variant) tagMap() map[string]SimpleType {
+	if v._map == nil {
+		v._map = make(map[string]SimpleType)
+		for _, tag := range v.Tags {
+			v._map[tag.Name] = tag.Type
+		}
+	}
+	return v._map
+}
+
 func (p *PolymorphicType) instantiate(t *Typer, lvl int) SimpleType {
 	return t.freshenAbove(p.level(), p.Body, lvl)
 }
@@ -111,6 +227,21 @@ func (v *Variable) asTypeVar() *types.TypeVariable {
 	return v._typeVar
 }
 
+// addLower addUpper 更新边界, 试探约束时记录撤销操作, 参见 Typer.trial
+func (t *Typer) addLower(v *Variable, st SimpleType) {
+	v.prependLower(st)
+	if t.trail != nil {
+		*t.trail = append(*t.trail, func() { v.LowerBounds = v.LowerBounds[1:] })
+	}
+}
+
+func (t *Typer) addUpper(v *Variable, st SimpleType) {
+	v.prependUpper(st)
+	if t.trail != nil {
+		*t.trail = append(*t.trail, func() { v.UpperBounds = v.UpperBounds[1:] })
+	}
+}
+
 func (v *Variable) prependLower(st SimpleType) {
 	v.LowerBounds = append(v.LowerBounds, nil)
 	copy(v.LowerBounds[1:], v.LowerBounds)
@@ -126,6 +257,9 @@ func (v *Variable) prependUpper(st SimpleType) {
 ////////////////////////////////////////////////////////////////////////////////
 
 func (p *Primitive) level() int       { return 0 }
+func (i *Intersection) level() int    { return 0 }
+func (u *Union) level() int           { return 0 }
+func (n *Negation) level() int        { return 0 }
 func (v *Variable) level() int        { return v._level }
 func (p *PolymorphicType) level() int { return p._level }
 func (f *Function) level() int {
@@ -158,6 +292,45 @@ func (r *Record) level() int {
 	}
 	return r._level
 }
+func (e *Extension) level() int {
+	if e._level < 0 {
+		e._level = e.Base.level()
+		for _, fd := range e.Fields {
+			lv := fd.Type.level()
+			if lv > e._level {
+				e._level = lv
+			}
+		}
+	}
+	return e._level
+}
+func (c *Constructor) level() int {
+	if c._level < 0 {
+		c._level = 0
+		for _, arg := range c.Args {
+			c._level = util.MaxInt(c._level, util.MaxInt(arg.In.level(), arg.Out.level()))
+		}
+	}
+	return c._level
+}
+func (r *Ref) level() int {
+	if r._level < 0 {
+		r._level = util.MaxInt(r.Write.level(), r.Read.level())
+	}
+	return r._level
+}
+func (v *Variant) level() int {
+	if v._level < 0 {
+		v._level = 0
+		for _, tag := range v.Tags {
+			lv := tag.Type.level()
+			if lv > v._level {
+				v._level = lv
+			}
+		}
+	}
+	return v._level
+}
 
 ////////////////////////////////////////////////////////////////////////////////
 
@@ -179,9 +352,54 @@ func (t *Tuple) hash() string {
 func (r *Record) hash() string {
 	if r._hash == "" {
 		r._hash = fmt.Sprintf("[%d]%s", r.level(), stringifyRecord(r, hashSimpleType))
+		if r.closed {
+			r._hash += "!"
+		}
+	}
+	return r._hash
+}
+func (e *Extension) hash() string {
+	if e._hash == "" {
+		e._hash = fmt.Sprintf("[%d]%s", e.level(), stringifyExtension(e, hashSimpleType))
+	}
+	return e._hash
+}
+func (c *Constructor) hash() string {
+	if c._hash == "" {
+		c._hash = fmt.Sprintf("[%d]%s", c.level(), stringifyCtor(c, hashSimpleType))
+	}
+	return c._hash
+}
+func (r *Ref) hash() string {
+	if r._hash == "" {
+		r._hash = fmt.Sprintf("[%d]ref %s, %s", r.level(), r.Write.hash(), r.Read.hash())
 	}
 	return r._hash
 }
+func (i *Intersection) hash() string {
+	if i._hash == "" {
+		i._hash = stringifyIntersection(i, hashSimpleType)
+	}
+	return i._hash
+}
+func (u *Union) hash() string {
+	if u._hash == "" {
+		u._hash = stringifyUnion(u, hashSimpleType)
+	}
+	return u._hash
+}
+func (n *Negation) hash() string {
+	if n._hash == "" {
+		n._hash = "~" + n.Neg.hash()
+	}
+	return n._hash
+}
+func (v *Variant) hash() string {
+	if v._hash == "" {
+		v._hash = fmt.Sprintf("[%d]%s", v.level(), stringifyVariant(v, hashSimpleType))
+	}
+	return v._hash
+}
 
 ////////////////////////////////////////////////////////////////////////////////
 
@@ -190,6 +408,19 @@ func (v *Variable) String() string  { return fmt.Sprintf("α%d%s", v.uid, string
 func (t *Tuple) String() string     { return stringifyTuple(t, stringifySimpleType) }
 func (r *Record) String() string    { return stringifyRecord(r, stringifySimpleType) }
 func (f *Function) String() string  { return fmt.Sprintf("(%s -> %s)", f.Lhs, f.Rhs) }
+func (v *Variant) String() string   { return stringifyVariant(v, stringifySimpleType) }
+func (e *Extension) String() string { return stringifyExtension(e, stringifySimpleType) }
+func (r *Ref) String() string       { return fmt.Sprintf("Ref[in %s, out %s]", r.Write, r.Read) }
+func (n *Negation) String() string  { return "~" + n.Neg.String() }
+func (c *Constructor) String() string {
+	return stringifyCtor(c, stringifySimpleType)
+}
+func (i *Intersection) String() string {
+	return stringifyIntersection(i, stringifySimpleType)
+}
+func (u *Union) String() string {
+	return stringifyUnion(u, stringifySimpleType)
+}
 
 func stringifyLevel(cnt int) string            { return strings.Repeat("'", cnt) }
 func stringifySimpleType(st SimpleType) string { return st.String() }
@@ -200,12 +431,62 @@ func stringifyTuple(t *Tuple, f func(t SimpleType) string) string {
 	for i, el := range t.Elms {
 		xs[i] = f(el)
 	}
+	// 单元素的 tuple 与括号区分, e.g. (int,)
+	if len(xs) == 1 {
+		return "(" + xs[0] + ",)"
+	}
 	return util.JoinStr(xs, ", ", "(", ")")
 }
 func stringifyRecord(r *Record, f func(t SimpleType) string) string {
 	xs := make([]string, len(r.Fields))
 	for i, fd := range r.Fields {
-		xs[i] = fmt.Sprintf("%s: %s", fd.Name, f(fd.Type))
+		if fd.Optional {
+			xs[i] = fmt.Sprintf("%s?: %s", fd.Name, f(fd.Type))
+		} else {
+			xs[i] = fmt.Sprintf("%s: %s", fd.Name, f(fd.Type))
+		}
 	}
 	return util.JoinStr(xs, ", ", "{", "}")
 }
+func stringifyExtension(e *Extension, f func(t SimpleType) string) string {
+	base := f(e.Base)
+	for _, name := range e.Removed {
+		base += " \\ " + name
+	}
+	xs := make([]string, len(e.Fields))
+	for i, fd := range e.Fields {
+		xs[i] = fmt.Sprintf("%s: %s", fd.Name, f(fd.Type))
+	}
+	return fmt.Sprintf("{%s with %s}", base, strings.Join(xs, ", "))
+}
+func stringifyVariant(v *Variant, f func(t SimpleType) string) string {
+	xs := make([]string, len(v.Tags))
+	for i, tag := range v.Tags {
+		xs[i] = fmt.Sprintf("%s %s", tag.Name, f(tag.Type))
+	}
+	return util.JoinStr(xs, " | ", "[", "]")
+}
+func stringifyIntersection(i *Intersection, f func(t SimpleType) string) string {
+	if len(i.Types) == 0 {
+		return "⊤"
+	}
+	xs := make([]string, len(i.Types))
+	for j, it := range i.Types {
+		xs[j] = f(it)
+	}
+	return util.JoinStr(xs, " ∧ ", "(", ")")
+}
+func stringifyUnion(u *Union, f func(t SimpleType) string) string {
+	xs := make([]string, len(u.Types))
+	for i, it := range u.Types {
+		xs[i] = f(it)
+	}
+	return util.JoinStr(xs, " ∨ ", "(", ")")
+}
+func stringifyCtor(c *Constructor, f func(t SimpleType) string) string {
+	xs := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		xs[i] = fmt.Sprintf("in %s, out %s", f(arg.In), f(arg.Out))
+	}
+	return c.Name + util.JoinStr(xs, "; ", "[", "]")
+}
@@ -33,9 +33,52 @@ func (t *Typer) coalesceType(st SimpleType) types.Type {
 			xs := make([]types.Field, len(ty.Fields))
 			for i, fd := range ty.Fields {
 				ft := do(fd.Type, pol, inProcess)
-				xs[i] = types.Field{Name: fd.Name, Type: ft}
+				xs[i] = types.Field{Name: fd.Name, Type: ft, Optional: fd.Optional}
 			}
 			return types.Record(xs)
+		case *Extension:
+			xs := make([]types.Field, len(ty.Fields))
+			for i, fd := range ty.Fields {
+				xs[i] = types.Field{Name: fd.Name, Type: do(fd.Type, pol, inProcess)}
+			}
+			return types.Extension(do(ty.Base, pol, inProcess), xs, ty.Removed)
+		case *Ref:
+			return types.Ref(do(ty.Write, !pol, inProcess), do(ty.Read, pol, inProcess))
+		case *Constructor:
+			vs := t.variances(ty.Name, len(ty.Args))
+			xs := make([]types.CtorArg, len(ty.Args))
+			for i, arg := range ty.Args {
+				if vs[i]&contravariant != 0 {
+					xs[i].In = do(arg.In, !pol, inProcess)
+				}
+				if vs[i]&covariant != 0 {
+					xs[i].Out = do(arg.Out, pol, inProcess)
+				}
+			}
+			return types.Ctor(ty.Name, xs)
+		case *Variant:
+			xs := make([]types.Field, len(ty.Tags))
+			for i, tag := range ty.Tags {
+				xs[i] = types.Field{Name: tag.Name, Type: do(tag.Type, pol, inProcess)}
+			}
+			return types.Variant(xs)
+		case *Intersection:
+			if len(ty.Types) == 0 {
+				return types.Top
+			}
+			res := do(ty.Types[0], pol, inProcess)
+			for _, it := range ty.Types[1:] {
+				res = types.Inter(res, do(it, pol, inProcess))
+			}
+			return res
+		case *Union:
+			res := do(ty.Types[0], pol, inProcess)
+			for _, it := range ty.Types[1:] {
+				res = types.Union(res, do(it, pol, inProcess))
+			}
+			return res
+		case *Negation:
+			return types.Neg(do(ty.Neg, !pol, inProcess))
 		case *Variable:
 			// 用极变量而不是变量做 key 保证只会生成"极"递归类型
 			pv := newPolarVar(ty, pol)
@@ -0,0 +1,148 @@
+package typer
+
+// 可比较类型
+//
+// eq 与 ord 是两个特殊的顶类型, 只包含可以比较相等与可以比较大小的值, 作为 == 与 < 等操作符参数的上界,
+// 比较函数时约束失败, e.g. (fun x -> x) == (fun x -> x)
+//
+//	bool, unit <: eq
+//	int, float, string <: ord <: eq
+//
+// 基本类型加入 primLattice, 参见 NewTyper, 声明的子类型与单例类型随之可比较, e.g. nat <: int <: ord
+// 化简时与基本类型一起合并, 参见 widenPrims, 于是只在必要时出现在推导结果中:
+//
+//	fun x -> x == 1       eq -> bool
+//	fun x -> x + 1 == 1   int -> bool   (int ∧ eq = int)
+//
+// 结构类型按分量判断:
+//
+//	(A, B) <: eq   即  A <: eq, B <: eq, ord 同理, 元组按字典序比较大小
+//	{a: A} <: eq   即  A <: eq, 标签联合、类同理, 不能比较大小
+//	Ref[A] <: eq   恒成立, 引用比较的是地址
+//
+// 函数、不透明的类型构造器等不可比较
+//
+// 比较操作符的两个参数共享以 eq 或 ord 为上界的类型变量, e.g. < : ∀𝛼 <: ord. 𝛼 → 𝛼 → bool
+// 但 int ∨ string <: ord 仍然成立, 所以给这样的变量加入下界时还要求与已有的下界同类, 参见 sameKind:
+//
+//	1 < 2.5     int, float 有子类型关系
+//	1 < "a"     cannot compare int with string
+
+// comparable 约束结构类型 lhs <: eq 或 lhs <: ord, lhs 不是结构类型时返回 false, 由 constrain 继续处理
+func (t *Typer) comparable(lhs SimpleType, rhs *Primitive, constrain func(SimpleType, SimpleType)) bool {
+	switch l := lhs.(type) {
+	case *Tuple:
+		for _, el := range l.Elms {
+			constrain(el, rhs)
+		}
+		return true
+	case *Record:
+		if rhs != Equatable {
+			return false
+		}
+		for _, fd := range l.Fields {
+			constrain(fd.Type, rhs)
+		}
+		return true
+	case *Extension:
+		if rhs != Equatable {
+			return false
+		}
+		for _, fd := range l.Fields {
+			constrain(fd.Type, rhs)
+		}
+		constrain(l.Base, rhs)
+		return true
+	case *Variant:
+		if rhs != Equatable {
+			return false
+		}
+		for _, tag := range l.Tags {
+			constrain(tag.Type, rhs)
+		}
+		return true
+	case *Ref:
+		return rhs == Equatable
+	case *Primitive:
+		if rhs != Equatable || !t.isClass(l) {
+			return false
+		}
+		for _, fd := range t.classFields(l) {
+			constrain(fd.Type, rhs)
+		}
+		return true
+	}
+	return false
+}
+
+// checkComparable 上界含 eq 或 ord 的变量 v 加入下界 lhs 之前, 检查 lhs 与已有的下界可以相互比较
+func (t *Typer) checkComparable(v *Variable, lhs SimpleType) {
+	bounded := false
+	for _, ub := range v.UpperBounds {
+		if ub == Equatable || ub == Ordered {
+			bounded = true
+		}
+	}
+	if !bounded {
+		return
+	}
+	for _, lb := range v.LowerBounds {
+		if !t.sameKind(lb, lhs) {
+			panic(NewTypeError("cannot compare %s with %s", t.show(lb), t.show(lhs)))
+		}
+	}
+}
+
+// sameKind 两个下界的值可以相互比较, 变量等无法判断的类型视为可以比较
+func (t *Typer) sameKind(lhs, rhs SimpleType) bool {
+	lhs, rhs = representative(lhs), representative(rhs)
+	switch l := lhs.(type) {
+	case *Primitive:
+		r, ok := rhs.(*Primitive)
+		if !ok {
+			return !isConcrete(rhs)
+		}
+		// 单例类型按基类型比较, e.g. "a" 与 "b"
+		if l.Base != nil {
+			l = l.Base
+		}
+		if r.Base != nil {
+			r = r.Base
+		}
+		return l == Dyn || r == Dyn || t.isSubPrim(l, r) || t.isSubPrim(r, l)
+	case *Tuple:
+		r, ok := rhs.(*Tuple)
+		if !ok {
+			return !isConcrete(rhs)
+		}
+		for i := 0; i < len(l.Elms) && i < len(r.Elms); i++ {
+			if !t.sameKind(l.Elms[i], r.Elms[i]) {
+				return false
+			}
+		}
+		return true
+	case *Record, *Extension:
+		switch rhs.(type) {
+		case *Record, *Extension:
+			return true
+		}
+	case *Variant:
+		_, ok := rhs.(*Variant)
+		return ok || !isConcrete(rhs)
+	case *Ref:
+		_, ok := rhs.(*Ref)
+		return ok || !isConcrete(rhs)
+	default:
+		return true
+	}
+	return !isConcrete(rhs)
+}
+
+// isConcrete 类型的外层结构已经确定
+func isConcrete(st SimpleType) bool {
+	switch st.(type) {
+	case *Primitive, *Tuple, *Record, *Extension, *Variant, *Ref, *Function:
+		return st != Dyn
+	}
+	return false
+}
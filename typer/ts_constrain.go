@@ -6,6 +6,12 @@ func (c cstCacheSet) key(lhs, rhs SimpleType) string    { return lhs.hash() + "
 func (c cstCacheSet) Add(lhs, rhs SimpleType)           { c[c.key(lhs, rhs)] = null }
 func (c cstCacheSet) Contains(lhs, rhs SimpleType) bool { _, ok := c[c.key(lhs, rhs)]; return ok }
 
+func (t *Typer) traceBound(v *Variable, b SimpleType, lower bool) {
+	if t.tracer != nil {
+		t.trace(&Event{Kind: EvBound, Level: v.level(), Polarity: polarity(lower), Lhs: v.String(), Rhs: b.String()})
+	}
+}
+
 // Constraining with levels.
 // 通过给 constraining 算法加入 level guard,
 // 确保 higher level 的类型变量不会 escape into lower level 类型变量的 bounds
@@ -17,30 +23,121 @@ func (t *Typer) constrain(lhs, rhs SimpleType) {
 
 	var do func(SimpleType, SimpleType)
 	do = func(lhs, rhs SimpleType) {
+		// 已合并的变量直接约束其代表变量
+		lhs, rhs = representative(lhs), representative(rhs)
 		if lhs == rhs {
 			return
 		}
 
 		lVar, lIsVar := lhs.(*Variable)
 		rVar, rIsVar := rhs.(*Variable)
-		if lIsVar || rIsVar {
+		_, lIsCtor := lhs.(*Constructor)
+		_, rIsCtor := rhs.(*Constructor)
+		if lIsVar || rIsVar || lIsCtor || rIsCtor {
 			// 没有必要缓存不涉及类型变量的子类型测试，因为只有类型变量的界可能成环
+			// 递归的构造器展开定义体时也可能成环
 			if cache.Contains(lhs, rhs) {
 				return
 			}
 			cache.Add(lhs, rhs)
 		}
 
+		if t.tracer != nil {
+			t.trace(&Event{Kind: EvConstrain, Lhs: lhs.String(), Rhs: rhs.String()})
+		}
+		t.depth++
+		defer func() { t.depth-- }()
+
+		// 动态类型与任何类型相容, 参见 ts_dynamic.go
+		// ? <: α 作为 α 的下界记录, 使推导结果展示动态值泄漏的位置, 它与任何上界相容, 不会引入类型错误
+		if lhs == Dyn && !rIsVar || rhs == Dyn {
+			t.consistent(lhs, rhs, do)
+			return
+		}
+
 		lPrim, lIsPrim := lhs.(*Primitive)
 		rPrim, rIsPrim := rhs.(*Primitive)
 		if lIsPrim && rIsPrim {
 			// lhs == rhs 已经处理过, prim 引用相等
 			// if lPrim.Name == rPrim.Name { return }
-			// int <: float
-			if lPrim == Int && rPrim == Float {
+			// 基本类型的子类型关系由 Typer 配置, 默认 int <: float, 参见 DeclarePrim
+			// 单例类型是其基类型的子类型, e.g. 42 <: int <: float
+			if t.isSubPrim(lPrim, rPrim) {
+				return
+			}
+		}
+
+		// 名义类型携带声明的字段, e.g. Point <: {x: 'a}
+		if rRcd, ok := rhs.(*Record); ok && lIsPrim && t.isClass(lPrim) {
+			fm := map[string]SimpleType{}
+			for _, fd := range t.classFields(lPrim) {
+				fm[fd.Name] = fd.Type
+			}
+			for _, rfd := range rRcd.Fields {
+				lTy, ok := fm[rfd.Name]
+				if !ok {
+					// 子类的实例可能有同名的字段, 类型未知
+					if rfd.Optional {
+						do(Top, rfd.Type)
+						continue
+					}
+					panic(NewTypeError("missing field: %s in %s", rfd.Name, t.show(lhs)))
+				}
+				do(lTy, rfd.Type)
+			}
+			return
+		}
+
+		// 结构类型按分量比较, 参见 ts_comparable.go
+		if (rhs == Equatable || rhs == Ordered) && !lIsVar && t.comparable(lhs, rPrim, do) {
+			return
+		}
+
+		// 重载: rhs 是交集时约束到每个成员, lhs 是交集时选择满足约束的成员
+		if rInter, ok := rhs.(*Intersection); ok {
+			for _, it := range rInter.Types {
+				do(lhs, it)
+			}
+			return
+		}
+		// 并集: lhs 是并集时约束每个成员, rhs 是并集时选择满足约束的成员, 参见 ts_union.go
+		if lUnion, ok := lhs.(*Union); ok && !rIsVar {
+			for _, it := range lUnion.Types {
+				do(it, rhs)
+			}
+			return
+		}
+		if rUnion, ok := rhs.(*Union); ok && !lIsVar {
+			t.member(lhs, rUnion, do)
+			return
+		}
+		// lhs <: ~N 即 lhs 与 N 不相交, ~M <: ~N 即 N <: M, 参见 ts_negation.go
+		if rNeg, ok := rhs.(*Negation); ok && !lIsVar {
+			if !t.disjoint(lhs, rNeg.Neg) {
+				panic(NewTypeError("cannot constrain %s <: %s", t.show(lhs), t.show(rhs)))
+			}
+			return
+		}
+		if lInter, ok := lhs.(*Intersection); ok && !rIsVar {
+			t.overload(lInter, rhs, do)
+			return
+		}
+
+		// 同名构造器按型变分解, 否则展开有定义体的构造器, 参见 typer_ctors.go
+		if lCtor, ok := lhs.(*Constructor); ok {
+			if rCtor, ok := rhs.(*Constructor); ok && lCtor.Name == rCtor.Name {
+				t.constrainCtor(lCtor, rCtor, do)
+				return
+			}
+			if !rIsVar && t.hasBody(lCtor) {
+				do(t.unfold(lCtor, true), rhs)
 				return
 			}
 		}
+		if rCtor, ok := rhs.(*Constructor); ok && !lIsVar && t.hasBody(rCtor) {
+			do(lhs, t.unfold(rCtor, false))
+			return
+		}
 
 		lFun, lIsFun := lhs.(*Function)
 		rFun, rIsFun := rhs.(*Function)
@@ -56,11 +153,22 @@ func (t *Typer) constrain(lhs, rhs SimpleType) {
 		if lIsRcd && rIsRcd {
 			lm := lRcd.fieldMap()
 			// 遍历 rhs 找 lhs, 宽度子类型, e.g. {a:int,b:string} <: {a:int}
+			// 可选字段可以缺失, e.g. {} <: {a?: int}, 但不能满足必需的字段, e.g. {a?: int} </: {a: int}
+			// 不是 closed 的 lhs 可能遗忘了该字段, 字段的类型是 ⊤, 参见 Record
 			for _, rfd := range rRcd.Fields {
 				lTy, ok := lm[rfd.Name]
 				if !ok {
+					if rfd.Optional {
+						if !lRcd.closed {
+							do(Top, rfd.Type)
+						}
+						continue
+					}
 					panic(NewTypeError("missing field: %s in %s", rfd.Name, t.show(lhs)))
 				}
+				if !rfd.Optional && lRcd.optional(rfd.Name) {
+					panic(NewTypeError("optional field: %s in %s", rfd.Name, t.show(lhs)))
+				}
 				rTy := rfd.Type
 				// 深度子类型 e.g. {a:int} <: {a:float}
 				do(lTy, rTy)
@@ -68,6 +176,77 @@ func (t *Typer) constrain(lhs, rhs SimpleType) {
 			return
 		}
 
+		lTup, lIsTup := lhs.(*Tuple)
+		rTup, rIsTup := rhs.(*Tuple)
+		if lIsTup && rIsTup {
+			// 宽度子类型与记录相同, 多出的元素被忽略, e.g. (int, bool) <: (int,)
+			if len(lTup.Elms) < len(rTup.Elms) {
+				panic(NewTypeError("missing element: _%d in %s", len(lTup.Elms)+1, t.show(lhs)))
+			}
+			for i, rTy := range rTup.Elms {
+				do(lTup.Elms[i], rTy)
+			}
+			return
+		}
+
+		lExt, lIsExt := lhs.(*Extension)
+		if lIsExt && rIsRcd {
+			lm := lExt.fieldMap()
+			// 扩展的字段直接约束, 删除的字段缺失, 其余字段由 Base 提供
+			var rest []field
+			for _, rfd := range rRcd.Fields {
+				if lTy, ok := lm[rfd.Name]; ok {
+					do(lTy, rfd.Type)
+				} else if lExt.removed(rfd.Name) {
+					if rfd.Optional {
+						continue
+					}
+					panic(NewTypeError("missing field: %s in %s", rfd.Name, t.show(lhs)))
+				} else {
+					rest = append(rest, rfd)
+				}
+			}
+			if len(rest) != 0 {
+				do(lExt.Base, Rcd(rest))
+			}
+			return
+		}
+
+		lRef, lIsRef := lhs.(*Ref)
+		rRef, rIsRef := rhs.(*Ref)
+		if lIsRef && rIsRef {
+			// 写入逆变, 读取协变
+			do(rRef.Write, lRef.Write)
+			do(lRef.Read, rRef.Read)
+			return
+		}
+
+		lVnt, lIsVnt := lhs.(*Variant)
+		rVnt, rIsVnt := rhs.(*Variant)
+		if lIsVnt && rIsVnt {
+			rm := rVnt.tagMap()
+			// 遍历 lhs 找 rhs, 与记录相反, e.g. [Some int] <: [Some int | None]
+			// rhs 来自 case 的分支, 缺少标签即 case 不完备
+			for _, ltag := range lVnt.Tags {
+				rTy, ok := rm[ltag.Name]
+				if !ok {
+					panic(NewTypeError("missing case: %s in %s", ltag.Name, t.show(rhs)))
+				}
+				// payload 协变
+				do(ltag.Type, rTy)
+			}
+			return
+		}
+
+		// α <: β 闭合了 β <: ... <: α, 合并环上的等价变量, 不再追加边界
+		// 试探约束时不合并, 合并无法撤销
+		if t.mergeVars && t.trail == nil && lIsVar && rIsVar {
+			if cycle := varPath(rVar, lVar); cycle != nil {
+				t.mergeCycle(cycle, lVar, do)
+				return
+			}
+		}
+
 		// 当 lhs 或 rhs 是类型变量时, 需要更新边界 (传统合一, 直接更新 subs)
 		// 修改边界后, 需要迭代被约束变量的相反边界, 确保与新边界约束一致
 
@@ -77,7 +256,8 @@ func (t *Typer) constrain(lhs, rhs SimpleType) {
 		// α <: rhs
 		if lIsVar && rhs.level() <= lhs.level() {
 			// 先更新上界, 重新约束下界
-			lVar.prependUpper(rhs)
+			t.addUpper(lVar, rhs)
+			t.traceBound(lVar, rhs, false)
 			// every lowerBound <: rhs
 			for _, lb := range lVar.LowerBounds {
 				do(lb, rhs)
@@ -87,8 +267,12 @@ func (t *Typer) constrain(lhs, rhs SimpleType) {
 
 		// lhs <: α
 		if rIsVar && lhs.level() <= rhs.level() {
+			if !lIsVar {
+				t.checkComparable(rVar, lhs)
+			}
 			// 先更新下界, 重新约束上界
-			rVar.prependLower(lhs)
+			t.addLower(rVar, lhs)
+			t.traceBound(rVar, lhs, true)
 			// lhs <: every upperBound
 			for _, ub := range rVar.UpperBounds {
 				do(lhs, ub)
@@ -0,0 +1,165 @@
+package typer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShowDot 将 SimpleType 可达的类型变量及其边界导出为 graphviz dot
+// 边的方向统一为子类型指向父类型: 下界 lb -> α (蓝色), 上界 α -> ub (红色)
+// 结构类型用方框表示, 虚线连接其中直接出现的类型变量
+func ShowDot(st SimpleType) string {
+	d := newDotWriter("bounds")
+	for _, v := range getVars(st) {
+		d.line("v%d [label=%q]", v.uid, fmt.Sprintf("%s\nlevel %d", v.String(), v.level()))
+	}
+	d.line("root [shape=point]")
+	d.line("root -> %s", d.node(st))
+	for _, v := range getVars(st) {
+		for _, lb := range v.LowerBounds {
+			d.line("%s -> v%d [color=blue]", d.node(lb), v.uid)
+		}
+		for _, ub := range v.UpperBounds {
+			d.line("v%d -> %s [color=red]", v.uid, d.node(ub))
+		}
+	}
+	return d.String()
+}
+
+// ShowCanonicalDot 导出 canonicalize 之后的 compactTypeScheme, 包括 recVars
+func (t *Typer) ShowCanonicalDot(st SimpleType) string {
+	return t.canonicalizeType(st).dot()
+}
+
+// dot 方框为 compactType, 椭圆为递归变量, α => 为递归变量的定义, 虚线连接 compactType 中出现的递归变量
+func (c *compactTypeScheme) dot() string {
+	d := newDotWriter("compact")
+	refs := func(id string, ty *compactType) {
+		for _, v := range compactVars(ty) {
+			if c.recVars.Get(v) != nil {
+				d.line("%s -> v%d [style=dashed]", id, v.uid)
+			}
+		}
+	}
+	d.line("term [shape=box, label=%q]", c.term.String())
+	refs("term", c.term)
+	for _, v := range c.recVars.Keys() {
+		body := c.recVars.Get(v)
+		d.line("v%d [label=%q]", v.uid, v.String())
+		d.line("r%d [shape=box, label=%q]", v.uid, body.String())
+		d.line("v%d -> r%d [label=\"=\"]", v.uid, v.uid)
+		refs(fmt.Sprintf("r%d", v.uid), body)
+	}
+	return d.String()
+}
+
+// compactVars compactType 中出现的所有类型变量, 按出现顺序去重
+func compactVars(ty *compactType) []*Variable {
+	var res []*Variable
+	set := varSet{}
+	var do func(*compactType)
+	do = func(ty *compactType) {
+		for _, v := range ty.vs.Values() {
+			if !set.Contains(v) {
+				set.Add(v)
+				res = append(res, v)
+			}
+		}
+		for _, el := range ty.tup {
+			do(el)
+		}
+		if ty.rec != nil {
+			for _, name := range ty.rec.Keys() {
+				do(ty.rec.Get(name))
+			}
+		}
+		for _, ext := range ty.ext {
+			do(ext.base)
+			for _, name := range ext.fields.Keys() {
+				do(ext.fields.Get(name))
+			}
+		}
+		if ty.vnt != nil {
+			for _, name := range ty.vnt.Keys() {
+				do(ty.vnt.Get(name))
+			}
+		}
+		if ty.ref != nil {
+			do(ty.ref.lhs)
+			do(ty.ref.rhs)
+		}
+		for _, ctor := range ty.ctor {
+			for _, arg := range ctor.args {
+				do(arg.lhs)
+				do(arg.rhs)
+			}
+		}
+		if ty.fun != nil {
+			do(ty.fun.lhs)
+			do(ty.fun.rhs)
+		}
+	}
+	do(ty)
+	return res
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type dotWriter struct {
+	b     strings.Builder
+	nodes map[string]string // 结构类型 hash -> node id
+}
+
+func newDotWriter(name string) *dotWriter {
+	d := &dotWriter{nodes: map[string]string{}}
+	d.b.WriteString("digraph " + name + " {\n")
+	d.line("node [fontname=monospace]")
+	return d
+}
+
+func (d *dotWriter) line(format string, a ...interface{}) {
+	d.b.WriteString("\t")
+	d.b.WriteString(fmt.Sprintf(format, a...))
+	d.b.WriteString(";\n")
+}
+
+// node 返回类型对应的节点, 结构类型首次出现时输出节点定义
+func (d *dotWriter) node(st SimpleType) string {
+	if v, ok := st.(*Variable); ok {
+		return fmt.Sprintf("v%d", v.uid)
+	}
+	h := st.hash()
+	if id, ok := d.nodes[h]; ok {
+		return id
+	}
+	id := fmt.Sprintf("s%d", len(d.nodes))
+	d.nodes[h] = id
+	d.line("%s [shape=box, label=%q]", id, st.String())
+	for _, v := range directVars(st) {
+		d.line("%s -> v%d [style=dashed, arrowhead=none]", id, v.uid)
+	}
+	return id
+}
+
+func (d *dotWriter) String() string { return d.b.String() + "}\n" }
+
+// directVars 结构类型中直接出现的类型变量, 不经过变量的边界
+func directVars(st SimpleType) []*Variable {
+	var res []*Variable
+	set := varSet{}
+	var do func(SimpleType)
+	do = func(st SimpleType) {
+		if v, ok := st.(*Variable); ok {
+			if !set.Contains(v) {
+				set.Add(v)
+				res = append(res, v)
+			}
+			return
+		}
+		for _, c := range children(st) {
+			do(c)
+		}
+	}
+	do(st)
+	return res
+}
@@ -0,0 +1,136 @@
+package typer
+
+import (
+	"fmt"
+
+	"github.com/goghcrow/simple-sub/terms"
+	"github.com/goghcrow/simple-sub/types"
+)
+
+// 渐进类型 (gradual typing)
+//
+// 动态类型 ? 与任意类型相容 (consistent), 两个方向的约束都成立, 用于调用无类型的宿主函数
+// 可以出现在类型标注与 builtins 的签名中, e.g. (x : ?), host: ? -> ?
+//
+// 约束 lhs <: rhs 的一侧是 ? 时不报错, 按另一侧的结构分解, 把 ? 传播到协变位置:
+//
+//	? <: A -> B    即  A <: ?, ? <: B
+//	A -> B <: ?    即  ? <: A, B <: ?
+//	? <: {a: A}    即  ? <: A, 元组、标签联合、引用同理
+//	? <: α         ? 作为 α 的下界记录, 并约束 α 的所有上界, 推导结果在正极展示为 ?
+//	α <: ?         恒成立, 不记录为上界
+//
+// ? <: α 是唯一记录 ? 的边界, 这是有意的: 不记录的话 host 1 的类型是没有下界的 α, 展示为 ⊥,
+// 推导结果就看不出动态值泄漏到了哪里. 这个下界不会污染 α:
+// 它与 α 之后的任何上界都相容 (只产生 Cast), 不会引入类型错误, 也不会约束 α 的静态部分,
+// 而 α <: ? 不记录, α 的上界中不会出现 ?
+//
+// 于是动态值流经的位置在推导结果中显示为 ?, e.g. fun x -> host x : 'a -> ?
+//
+// ? 流入不能再分解的静态类型 (基本类型、名义类型、构造器等) 时, 运行时需要在此检查值的类型,
+// 记录为 Cast, 以推导该约束时所在的表达式作为 blame label, 检查失败时归咎于它
+// 只在基本类型的边界检查, 函数不需要包装 (类似 transient 语义)
+//
+// 本仓库没有求值器, 也没有把 Cast 插入 term 的 elaboration, 只记录需要插入的转换:
+// 转换按所在的 term (引用) 记录在 Typer 上, 每次推导程序时重置, 参见 Casts, CastsAt
+// 嵌入方的求值器在求值 Site 时按 Type 检查动态值, 失败时以 Label 报告 blame
+
+// Cast 运行时类型转换 ? => Type, 插入在 Site 处, 转换失败时归咎于 Site, Label 是 Site 的源码
+type Cast struct {
+	Site  terms.Term
+	Label string
+	Type  types.Type
+}
+
+func (c Cast) String() string { return fmt.Sprintf("%s: ? => %s", c.Label, c.Type.Show()) }
+
+// Casts 最近一次推导的程序需要插入的运行时转换, 按推导顺序
+func (t *Typer) Casts() []Cast { return t.casts }
+
+// CastsAt 最近一次推导的程序在 site 处需要插入的运行时转换, 按 term 的引用区分源码相同的位置
+func (t *Typer) CastsAt(site terms.Term) []Cast {
+	var xs []Cast
+	for _, c := range t.casts {
+		if c.Site == site {
+			xs = append(xs, c)
+		}
+	}
+	return xs
+}
+
+// consistent 约束 lhs <: rhs, 其中一侧是 ?
+func (t *Typer) consistent(lhs, rhs SimpleType, constrain func(SimpleType, SimpleType)) {
+	if lhs == Dyn {
+		switch r := rhs.(type) {
+		case *Function:
+			constrain(r.Lhs, Dyn)
+			constrain(Dyn, r.Rhs)
+		case *Tuple:
+			for _, el := range r.Elms {
+				constrain(Dyn, el)
+			}
+		case *Record:
+			for _, fd := range r.Fields {
+				constrain(Dyn, fd.Type)
+			}
+		case *Variant:
+			for _, tag := range r.Tags {
+				constrain(Dyn, tag.Type)
+			}
+		case *Ref:
+			constrain(r.Write, Dyn)
+			constrain(Dyn, r.Read)
+		case *Intersection:
+			for _, it := range r.Types {
+				constrain(Dyn, it)
+			}
+		default:
+			t.cast(rhs)
+		}
+		return
+	}
+	switch l := lhs.(type) {
+	case *Function:
+		constrain(Dyn, l.Lhs)
+		constrain(l.Rhs, Dyn)
+	case *Tuple:
+		for _, el := range l.Elms {
+			constrain(el, Dyn)
+		}
+	case *Record:
+		for _, fd := range l.Fields {
+			constrain(fd.Type, Dyn)
+		}
+	case *Variant:
+		for _, tag := range l.Tags {
+			constrain(tag.Type, Dyn)
+		}
+	case *Ref:
+		constrain(Dyn, l.Write)
+		constrain(l.Read, Dyn)
+	}
+}
+
+// cast 记录 ? => st, 同一位置重复的转换只记录一次
+func (t *Typer) cast(st SimpleType) {
+	c := Cast{Site: t.site, Type: t.coalesceType(st)}
+	if t.site != nil {
+		c.Label = terms.ShowTerm(t.site)
+	}
+	for _, it := range t.casts {
+		if it.Site == c.Site && types.Equal(it.Type, c.Type) {
+			return
+		}
+	}
+	t.casts = append(t.casts, c)
+	if t.trail != nil {
+		*t.trail = append(*t.trail, func() { t.casts = t.casts[:len(t.casts)-1] })
+	}
+}
+
+// enter 进入 term, 返回恢复之前位置的函数, 参见 cast
+func (t *Typer) enter(term terms.Term) func() {
+	site := t.site
+	t.site = term
+	return func() { t.site = site }
+}
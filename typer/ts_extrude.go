@@ -36,10 +36,31 @@ func (t *Typer) extrude(st SimpleType, pol bool, lvl int) SimpleType {
 		case *Record:
 			xs := make([]field, len(ty.Fields))
 			for i, fd := range ty.Fields {
-				xs[i] = field{fd.Name, do(fd.Type, pol, lvl)}
+				xs[i] = field{fd.Name, do(fd.Type, pol, lvl), fd.Optional}
 			}
-			return Rcd(xs)
+			return ty.with(xs)
+		case *Extension:
+			xs := make([]field, len(ty.Fields))
+			for i, fd := range ty.Fields {
+				xs[i] = field{fd.Name, do(fd.Type, pol, lvl), fd.Optional}
+			}
+			return Ext(do(ty.Base, pol, lvl), xs, ty.Removed)
+		case *Ref:
+			return RefT(do(ty.Write, !pol, lvl), do(ty.Read, pol, lvl))
+		case *Constructor:
+			xs := make([]ctorArg, len(ty.Args))
+			for i, arg := range ty.Args {
+				xs[i] = ctorArg{do(arg.In, !pol, lvl), do(arg.Out, pol, lvl)}
+			}
+			return CtorX(ty.Name, xs)
+		case *Variant:
+			xs := make([]field, len(ty.Tags))
+			for i, tag := range ty.Tags {
+				xs[i] = field{Name: tag.Name, Type: do(tag.Type, pol, lvl)}
+			}
+			return Vnt(xs)
 		case *Variable:
+			ty = ty.find()
 			pv := newPolarVar(ty, pol)
 			extruded := cache.Get(pv)
 			if extruded != nil {
@@ -49,25 +70,31 @@ func (t *Typer) extrude(st SimpleType, pol bool, lvl int) SimpleType {
 			nvs := t.freshVar(lvl)
 			cache.Put(pv, nvs)
 			if pol {
-				ty.prependUpper(nvs)
+				t.addUpper(ty, nvs)
+				t.traceBound(ty, nvs, false)
 				nvs.LowerBounds = make([]SimpleType, len(ty.LowerBounds))
 				for i, b := range ty.LowerBounds {
 					nvs.LowerBounds[i] = do(b, pol, lvl)
 				}
 			} else {
-				ty.prependLower(nvs)
+				t.addLower(ty, nvs)
+				t.traceBound(ty, nvs, true)
 				nvs.UpperBounds = make([]SimpleType, len(ty.UpperBounds))
 				for i, b := range ty.UpperBounds {
 					nvs.UpperBounds[i] = do(b, pol, lvl)
 				}
 			}
 			return nvs
-		case *Primitive:
+		case *Primitive, *Intersection, *Union, *Negation:
 			return ty
 		default:
 			panic("unreached")
 		}
 	}
 
-	return do(st, pol, lvl)
+	res := do(st, pol, lvl)
+	if t.tracer != nil {
+		t.trace(&Event{Kind: EvExtrude, Level: lvl, Polarity: polarity(pol), Lhs: st.String(), Rhs: res.String()})
+	}
+	return res
 }
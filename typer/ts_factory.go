@@ -1,28 +1,153 @@
 package typer
 
-import "github.com/goghcrow/simple-sub/util"
+import (
+	"github.com/goghcrow/simple-sub/util"
+	"sort"
+)
 
 var (
-	Bool   = Prim("bool")
-	Int    = Prim("int")
-	Float  = Prim("float")
-	String = Prim("string")
+	Bool   = &Primitive{Name: "bool"}
+	Int    = &Primitive{Name: "int"}
+	Float  = &Primitive{Name: "float"}
+	String = &Primitive{Name: "string"}
+	Unit   = &Primitive{Name: "unit"}
+	// Top 空交集, 任意类型都是它的子类型
+	Top = Inters()
+	// Dyn 动态类型 ?, 与任意类型相容, 参见 ts_dynamic.go
+	Dyn = &Primitive{Name: "?"}
+	// Equatable, Ordered 可比较相等与可比较大小的类型, 参见 ts_comparable.go
+	Equatable = &Primitive{Name: "eq"}
+	Ordered   = &Primitive{Name: "ord"}
 )
 
-var primitives = map[string]*Primitive{}
+// builtinPrims 内置的基本类型, 所有 Typer 共享, 其余基本类型由各个 Typer 的 typeDecls.names 管理
+var builtinPrims = []*Primitive{Bool, Int, Float, String, Unit, Dyn, Equatable, Ordered}
 
-func Prim(name string) *Primitive {
-	p := primitives[name]
-	if p == nil {
-		p = &Primitive{Name: name}
-		primitives[name] = p
-	}
-	return p
+// Lit 字面量的单例类型, 以字面量的源码表示命名, e.g. 42, 3.14, "GET"
+// 单例类型不驻留, 按值比较, 参见 samePrim
+func Lit(name string, base *Primitive) *Primitive {
+	return &Primitive{Name: name, Base: base}
+}
+
+// samePrim 同一个 Typer 中基本类型按名字唯一, 引用相等; 单例类型按基类型与名字相等
+func samePrim(p, q *Primitive) bool {
+	return p == q || p.Base != nil && p.Base == q.Base && p.Name == q.Name
 }
 
 func Fun(lhs SimpleType, rhs SimpleType) *Function { return &Function{Lhs: lhs, Rhs: rhs, _level: -1} }
 func Tup(elms []SimpleType) *Tuple                 { return &Tuple{Elms: elms, _level: -1} }
 func Rcd(fields []field) *Record                   { return &Record{Fields: fields, _level: -1} }
+func Vnt(tags []field) *Variant                    { return &Variant{Tags: tags, _level: -1} }
+func RefT(write, read SimpleType) *Ref             { return &Ref{Write: write, Read: read, _level: -1} }
+func Inters(types ...SimpleType) *Intersection     { return &Intersection{Types: types} }
+func Ext(base SimpleType, fields []field, removed []string) *Extension {
+	return &Extension{Base: base, Fields: fields, Removed: removed, _level: -1}
+}
+
+// Ctor 类型构造器的应用, 参数的逆变分量与协变分量相同, e.g. Ctor("Box", Int) 即 Box[int]
+func Ctor(name string, args ...SimpleType) *Constructor {
+	xs := make([]ctorArg, len(args))
+	for i, arg := range args {
+		xs[i] = ctorArg{arg, arg}
+	}
+	return CtorX(name, xs)
+}
+func CtorX(name string, args []ctorArg) *Constructor {
+	return &Constructor{Name: name, Args: args, _level: -1}
+}
+
+// closedRcd 记录字面量的类型, 参见 Record
+func closedRcd(fields []field) *Record {
+	r := Rcd(fields)
+	r.closed = true
+	return r
+}
+
+// with 替换字段, 保留 closed
+func (r *Record) with(fields []field) *Record {
+	x := Rcd(fields)
+	x.closed = r.closed
+	return x
+}
+
+// extend 基类型 base 删除 removed 字段并扩展 fields 字段
+// base 是记录时直接折叠为记录, base 是扩展时合并为一层
+func extend(base SimpleType, fields []field, removed []string) SimpleType {
+	overridden := map[string]bool{}
+	for _, fd := range fields {
+		overridden[fd.Name] = true
+	}
+	gone := map[string]bool{}
+	for _, name := range removed {
+		gone[name] = !overridden[name]
+	}
+	keep := func(fds []field) []field {
+		xs := make([]field, 0, len(fds)+len(fields))
+		for _, fd := range fds {
+			if !overridden[fd.Name] && !gone[fd.Name] {
+				xs = append(xs, fd)
+			}
+		}
+		return append(xs, fields...)
+	}
+
+	switch ty := base.(type) {
+	case *Record:
+		return ty.with(keep(ty.Fields))
+	case *Extension:
+		for _, name := range ty.Removed {
+			gone[name] = gone[name] || !overridden[name]
+		}
+		return Ext(ty.Base, keep(ty.Fields), sortedNames(gone))
+	default:
+		return Ext(base, fields, sortedNames(gone))
+	}
+}
+
+// Unions 并集, 展开嵌套的并集, 按 hash 去重, 只有一个成员时即该成员
+func Unions(types ...SimpleType) SimpleType {
+	var xs []SimpleType
+	seen := map[string]bool{}
+	var add func(st SimpleType)
+	add = func(st SimpleType) {
+		if u, ok := st.(*Union); ok {
+			for _, it := range u.Types {
+				add(it)
+			}
+			return
+		}
+		if !seen[st.hash()] {
+			seen[st.hash()] = true
+			xs = append(xs, st)
+		}
+	}
+	for _, st := range types {
+		add(st)
+	}
+	if len(xs) == 1 {
+		return xs[0]
+	}
+	return &Union{Types: xs}
+}
+
+// Neg 否定类型, ~~T = T
+func Neg(st SimpleType) SimpleType {
+	if n, ok := st.(*Negation); ok {
+		return n.Neg
+	}
+	return &Negation{Neg: st}
+}
+
+func sortedNames(set map[string]bool) []string {
+	xs := make([]string, 0, len(set))
+	for name, ok := range set {
+		if ok {
+			xs = append(xs, name)
+		}
+	}
+	sort.Strings(xs)
+	return xs
+}
 
 func PolyType(lvl int, body SimpleType) *PolymorphicType {
 	return &PolymorphicType{Body: body, _level: lvl}
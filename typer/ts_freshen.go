@@ -17,7 +17,7 @@ func (t *Typer) freshenAbove(lim int, st SimpleType, lvl int) SimpleType {
 		}
 
 		switch ty := st.(type) {
-		case *Primitive:
+		case *Primitive, *Intersection, *Union, *Negation:
 			return ty
 		case *Function:
 			return Fun(freshen(ty.Lhs), freshen(ty.Rhs))
@@ -30,10 +30,34 @@ func (t *Typer) freshenAbove(lim int, st SimpleType, lvl int) SimpleType {
 		case *Record:
 			xs := make([]field, len(ty.Fields))
 			for i, fd := range ty.Fields {
-				xs[i] = field{fd.Name, freshen(fd.Type)}
+				xs[i] = field{fd.Name, freshen(fd.Type), fd.Optional}
 			}
-			return Rcd(xs)
+			return ty.with(xs)
+		case *Extension:
+			xs := make([]field, len(ty.Fields))
+			for i, fd := range ty.Fields {
+				xs[i] = field{fd.Name, freshen(fd.Type), fd.Optional}
+			}
+			return Ext(freshen(ty.Base), xs, ty.Removed)
+		case *Ref:
+			return RefT(freshen(ty.Write), freshen(ty.Read))
+		case *Constructor:
+			xs := make([]ctorArg, len(ty.Args))
+			for i, arg := range ty.Args {
+				xs[i] = ctorArg{freshen(arg.In), freshen(arg.Out)}
+			}
+			return CtorX(ty.Name, xs)
+		case *Variant:
+			xs := make([]field, len(ty.Tags))
+			for i, tag := range ty.Tags {
+				xs[i] = field{Name: tag.Name, Type: freshen(tag.Type)}
+			}
+			return Vnt(xs)
 		case *Variable:
+			if ty._link != nil {
+				// 已合并的变量与代表变量共享副本
+				return freshen(ty.find())
+			}
 			vs, ok := freshened.Get(ty)
 			if ok {
 				return vs
@@ -1,6 +1,12 @@
 package typer
 
-import "github.com/goghcrow/simple-sub/terms"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/goghcrow/simple-sub/terms"
+)
 
 // 注意这里 会将 rhs(let-body) 的类型 wrap 成 PolymorphicType,
 // PolymorphicType 简单包装 SimpleType 的 let-body, 并额外记录 above which level
@@ -9,16 +15,40 @@ import "github.com/goghcrow/simple-sub/terms"
 // 注意 let 需要 level + 1, 低于当前 level 的 type var 能逃逸当前环境的约束
 func (t *Typer) typeLetRhs(let *terms.Declaration, ctx *Ctx, lvl int) *PolymorphicType {
 	if let.Rec {
-		// 为 let-binding rhs 在 context 绑定一个类型变量, 之后检查( constrain )其为 实际的 rhs 类型的 supertype
-		eTy := t.freshVar(lvl + 1)
-		ctx = ctx.Extend(let.Name, eTy)
-		ty := t.typeTerm(let.Rhs, ctx, lvl+1)
-		t.constrain(ty, eTy)
-		return PolyType(lvl, eTy)
-	} else {
-		ty := t.typeTerm(let.Rhs, ctx, lvl+1)
-		return PolyType(lvl, ty)
+		return t.typeRecGroup([]*terms.Declaration{let}, ctx, lvl)[0]
+	}
+	if !isValue(let.Rhs) {
+		// value restriction: 非值的 rhs (e.g. ref e, f x) 求值可能创建引用, 不能泛化
+		// 直接在当前 level 推导, rhs 中的类型变量不会高于 lvl
+		// 闭包捕获的引用不出现在类型中, 所以不能只看类型里有没有 Ref
+		return PolyType(lvl, t.typeTerm(let.Rhs, ctx, lvl))
+	}
+	return PolyType(lvl, t.typeTerm(let.Rhs, ctx, lvl+1))
+}
+
+// typeRecGroup 互递归的一组定义, let rec f = ... and g = ...
+// 先为每个 let-binding 在 context 绑定一个 lvl+1 的类型变量,
+// 之后检查( constrain )其为 实际的 rhs 类型的 supertype, 最后一起泛化
+func (t *Typer) typeRecGroup(defs []*terms.Declaration, ctx *Ctx, lvl int) []*PolymorphicType {
+	eTys := make([]*Variable, len(defs))
+	seen := map[string]bool{}
+	for i, def := range defs {
+		if seen[def.Name] {
+			panic(NewTypeError("duplicate definition in let rec group: %s", def.Name))
+		}
+		seen[def.Name] = true
+		eTys[i] = t.freshVar(lvl + 1)
+		ctx = ctx.Extend(def.Name, eTys[i])
+	}
+	res := make([]*PolymorphicType, len(defs))
+	for i, def := range defs {
+		ty := t.typeTerm(def.Rhs, ctx, lvl+1)
+		leave := t.enter(def.Rhs)
+		t.constrain(ty, eTys[i])
+		leave()
+		res[i] = PolyType(lvl, eTys[i])
 	}
+	return res
 }
 
 // 类型推导
@@ -26,15 +56,27 @@ func (t *Typer) typeLetRhs(let *terms.Declaration, ctx *Ctx, lvl int) *Polymorph
 // 核心函数, 除了 constrain 与传统 HM 合一类似
 // 根据上下文推导出 term 的 SimpleType, 其中约束函数作为补充, 把一个类型约束为另一个类型的子类型, 否则报错
 func (t *Typer) typeTerm(term terms.Term, ctx *Ctx, lvl int) SimpleType {
+	defer t.enter(term)()
 	switch tm := term.(type) {
 	case *terms.LiteralBool:
 		return Bool
 	case *terms.LiteralInt:
+		if t.singletons {
+			return litType(tm)
+		}
 		return Int
 	case *terms.LiteralFloat:
+		if t.singletons {
+			return litType(tm)
+		}
 		return Float
 	case *terms.LiteralString:
+		if t.singletons {
+			return litType(tm)
+		}
 		return String
+	case *terms.LiteralUnit:
+		return Unit
 	case *terms.Variable:
 		varTy := ctx.MustLookup(tm.Name)
 		// HM 因为 let 多态的原因, var 的需要 instantiate
@@ -59,7 +101,7 @@ func (t *Typer) typeTerm(term terms.Term, ctx *Ctx, lvl int) SimpleType {
 	case *terms.Selection:
 		rcdLhs := t.typeTerm(tm.Recv, ctx, lvl)
 		fd := t.freshVar(lvl)
-		rcdRhs := Rcd([]field{{tm.FieldName, fd}})
+		rcdRhs := Rcd([]field{{Name: tm.FieldName, Type: fd}})
 		// record.field
 		// record <: record {field: T}
 		// lhs receiver 是一个必须包含 field 字段的记录类型
@@ -67,6 +109,32 @@ func (t *Typer) typeTerm(term terms.Term, ctx *Ctx, lvl int) SimpleType {
 		// {a:1,b:"s"}.a =>  {a:int, b:string} <: {a:int}
 		t.constrain(rcdLhs, rcdRhs)
 		return fd
+	case *terms.Projection:
+		// t._n
+		// t <: (α1, ..., αn), 元素个数不少于 n 的 tuple 都是其子类型
+		// e.g. (1, true, "s")._2 => (int, bool, string) <: (α1, α2)
+		recv := t.typeTerm(tm.Recv, ctx, lvl)
+		xs := make([]SimpleType, tm.Index)
+		for i := range xs {
+			xs[i] = t.freshVar(lvl)
+		}
+		// 接收者不是 tuple 时回退为字段选择, e.g. {_1: 1}._1 => {_1: int} <: {_1: α}
+		// 类型未知的接收者 (e.g. lambda 参数) 按 tuple 推导
+		if fd := Rcd([]field{{Name: fmt.Sprintf("_%d", tm.Index), Type: xs[0]}}); !t.trial(recv, Tup(xs)) && t.trial(recv, fd) {
+			t.constrain(recv, fd)
+			return xs[0]
+		}
+		t.constrain(recv, Tup(xs))
+		return xs[tm.Index-1]
+	case *terms.Coalesce:
+		// r.a ?? d
+		// r <: {a?: α}, d <: α, 没有字段 a 的记录也是其子类型
+		// e.g. {}.a ?? 1 => {} <: {a?: α}, int <: α
+		// 可能遗忘了字段 a 的记录缺少 a 时, a 的类型是 ⊤, 参见 Record
+		res := t.freshVar(lvl)
+		t.constrain(t.typeTerm(tm.Recv, ctx, lvl), Rcd([]field{{tm.FieldName, res, true}}))
+		t.constrain(t.typeTerm(tm.Default, ctx, lvl), res)
+		return res
 	case *terms.Tuple:
 		xs := make([]SimpleType, len(tm.Elms))
 		for i, el := range tm.Elms {
@@ -76,9 +144,82 @@ func (t *Typer) typeTerm(term terms.Term, ctx *Ctx, lvl int) SimpleType {
 	case *terms.Record:
 		xs := make([]field, len(tm.Fields))
 		for i, fd := range tm.Fields {
-			xs[i] = field{fd.Name, t.typeTerm(fd.Term, ctx, lvl)}
+			xs[i] = field{Name: fd.Name, Type: t.typeTerm(fd.Term, ctx, lvl)}
+		}
+		return closedRcd(xs)
+	case *terms.Extend:
+		// {r with a: e}, r <: {}
+		recv := t.typeTerm(tm.Recv, ctx, lvl)
+		t.constrain(recv, Rcd([]field{}))
+		xs := make([]field, len(tm.Fields))
+		for i, fd := range tm.Fields {
+			xs[i] = field{Name: fd.Name, Type: t.typeTerm(fd.Term, ctx, lvl)}
+		}
+		return extend(recv, xs, nil)
+	case *terms.Restrict:
+		// r \ a, r <: {}
+		recv := t.typeTerm(tm.Recv, ctx, lvl)
+		t.constrain(recv, Rcd([]field{}))
+		return extend(recv, nil, []string{tm.FieldName})
+	case *terms.Tag:
+		if p := t.names[tm.Name]; t.isClass(p) {
+			return t.construct(p, tm, ctx, lvl)
+		}
+		var arg SimpleType = closedRcd([]field{})
+		if tm.Arg != nil {
+			arg = t.typeTerm(tm.Arg, ctx, lvl)
 		}
-		return Rcd(xs)
+		return Vnt([]field{{Name: tm.Name, Type: arg}})
+	case *terms.Case:
+		// scrutinee <: [C0 α0 | ... | Cn αn], 完备性由 scrutinee 的类型决定
+		// 每个分支的 body <: res
+		scrut := t.typeTerm(tm.Scrut, ctx, lvl)
+		tags := make([]field, len(tm.Arms))
+		seen := map[string]bool{}
+		for i, arm := range tm.Arms {
+			if seen[arm.Tag] {
+				panic(NewTypeError("duplicate case: %s", arm.Tag))
+			}
+			seen[arm.Tag] = true
+			tags[i] = field{Name: arm.Tag, Type: t.freshVar(lvl)}
+		}
+		t.constrain(scrut, Vnt(tags))
+		res := t.freshVar(lvl)
+		for i, arm := range tm.Arms {
+			nctx := ctx
+			if arm.Name != "" {
+				nctx = ctx.Extend(arm.Name, tags[i].Type)
+			}
+			t.constrain(t.typeTerm(arm.Body, nctx, lvl), res)
+		}
+		return res
+	case *terms.LetGroup:
+		nctx := ctx
+		for i, ty := range t.typeRecGroup(tm.Defs, ctx, lvl) {
+			nctx = nctx.Extend(tm.Defs[i].Name, ty)
+		}
+		return t.typeTerm(tm.Body, nctx, lvl)
+	case *terms.NewRef:
+		// ref e : Ref[in α, out α], e <: α
+		cell := t.freshVar(lvl)
+		t.constrain(t.typeTerm(tm.Init, ctx, lvl), cell)
+		return RefT(cell, cell)
+	case *terms.Deref:
+		// r <: Ref[in ⊥, out α], 写入类型不受约束
+		res := t.freshVar(lvl)
+		t.constrain(t.typeTerm(tm.Ref, ctx, lvl), RefT(t.freshVar(lvl), res))
+		return res
+	case *terms.Assign:
+		// r <: Ref[in e, out ⊤]
+		ref := t.typeTerm(tm.Ref, ctx, lvl)
+		rhs := t.typeTerm(tm.Rhs, ctx, lvl)
+		t.constrain(ref, RefT(rhs, t.freshVar(lvl)))
+		return Unit
+	case *terms.Ascribe:
+		// (e : T) : T, e <: T
+		ty := t.typeExpr(tm.Type)
+		t.constrain(t.typeTerm(tm.Term, ctx, lvl), ty)
+		return ty
 	case *terms.LetDefine:
 		nTy := t.typeLetRhs(&tm.Declaration, ctx, lvl)
 		nctx := ctx.Extend(tm.Name, nTy)
@@ -92,7 +233,76 @@ func (t *Typer) typeTerm(term terms.Term, ctx *Ctx, lvl int) SimpleType {
 // 并把 above level 的类型变量替换为 level lvl 的 fresh variables (freshenAbove 的工作)
 func (t *Typer) instantiate(ty TypeScheme, lvl int) SimpleType {
 	if p, ok := ty.(*PolymorphicType); ok {
-		return p.instantiate(t, lvl)
+		res := p.instantiate(t, lvl)
+		if t.tracer != nil {
+			t.trace(&Event{Kind: EvInstantiate, Level: lvl, Lhs: p.Body.String(), Rhs: res.String()})
+		}
+		return res
 	}
 	return ty.(SimpleType)
 }
+
+// isValue 语法上的值 (non-expansive), 求值不会创建引用, 可以安全泛化
+func isValue(term terms.Term) bool {
+	switch tm := term.(type) {
+	case *terms.LiteralBool, *terms.LiteralInt, *terms.LiteralFloat, *terms.LiteralString, *terms.LiteralUnit,
+		*terms.Variable, *terms.Lambda:
+		return true
+	case *terms.Tuple:
+		for _, el := range tm.Elms {
+			if !isValue(el) {
+				return false
+			}
+		}
+		return true
+	case *terms.Record:
+		for _, fd := range tm.Fields {
+			if !isValue(fd.Term) {
+				return false
+			}
+		}
+		return true
+	case *terms.Selection:
+		return isValue(tm.Recv)
+	case *terms.Projection:
+		return isValue(tm.Recv)
+	case *terms.Coalesce:
+		return isValue(tm.Recv) && isValue(tm.Default)
+	case *terms.Extend:
+		for _, fd := range tm.Fields {
+			if !isValue(fd.Term) {
+				return false
+			}
+		}
+		return isValue(tm.Recv)
+	case *terms.Restrict:
+		return isValue(tm.Recv)
+	case *terms.Tag:
+		return tm.Arg == nil || isValue(tm.Arg)
+	case *terms.LetDefine:
+		return isValue(tm.Rhs) && isValue(tm.Body)
+	case *terms.LetGroup:
+		return isValue(tm.Body)
+	default:
+		return false
+	}
+}
+
+// litType 字面量的单例类型, 也用于类型标注中的字面量类型, e.g. 42, 3.0, "GET"
+func litType(lit terms.Term) *Primitive {
+	switch tm := lit.(type) {
+	case *terms.LiteralInt:
+		return Lit(strconv.FormatInt(tm.Val, 10), Int)
+	case *terms.LiteralFloat:
+		// 保留小数点, 避免与整数字面量同名
+		s := strconv.FormatFloat(tm.Val, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return Lit(s, Float)
+	case *terms.LiteralString:
+		return Lit(strconv.Quote(tm.Val), String)
+	default:
+		panic("unreached")
+	}
+}
@@ -0,0 +1,141 @@
+package typer
+
+import "fmt"
+
+// 类型变量合并 (union-find)
+//
+// 每个 α <: β 约束都会追加边界, 长的变量链会产生巨大的传递边界图, closeOver 之后需要遍历。
+// 约束 α <: β 时, 如果已经有 β <: ... <: α (经由类型变量上界), 则环上所有变量互为子类型, 即等价。
+// 根据 level 约束 (边界的 level 不高于变量自身), 环上变量的 level 一定相同。
+//
+// 合并:
+//
+//	选 uid 最小的变量做代表, 代表变量持有环上所有变量的边界 (去掉环上变量自身)。
+//	其他变量 _link 指向代表变量, 边界只保留代表变量 (m :> rep <: m), 不识别 _link 的 pass 依然正确,
+//	之后的约束通过 find 直接作用于代表变量。
+
+// find 返回 v 所在等价类的代表变量
+func (v *Variable) find() *Variable {
+	r := v
+	for r._link != nil {
+		r = r._link
+	}
+	// 路径压缩
+	for v._link != nil && v._link != r {
+		next := v._link
+		v._link = r
+		v = next
+	}
+	return r
+}
+
+// representative 如果 st 是类型变量, 返回其代表变量
+func representative(st SimpleType) SimpleType {
+	if v, ok := st.(*Variable); ok {
+		return v.find()
+	}
+	return st
+}
+
+// varPath 查找从 from 出发, 经由同 level 类型变量上界到达 to 的路径, 不存在返回 nil
+func varPath(from, to *Variable) []*Variable {
+	lvl := to.level()
+	visited := varSet{}
+	var path []*Variable
+
+	var dfs func(*Variable) bool
+	dfs = func(v *Variable) bool {
+		if v == to {
+			path = append(path, v)
+			return true
+		}
+		if visited.Contains(v) {
+			return false
+		}
+		visited.Add(v)
+		for _, ub := range v.UpperBounds {
+			w, ok := ub.(*Variable)
+			if !ok {
+				continue
+			}
+			w = w.find()
+			if w.level() == lvl && dfs(w) {
+				path = append(path, v)
+				return true
+			}
+		}
+		return false
+	}
+
+	if from.level() != lvl || !dfs(from) {
+		return nil
+	}
+	return path
+}
+
+// mergeCycle 合并环上的等价变量, 返回代表变量
+// lhs 为闭合环的约束 lhs <: rhs 的左侧, 它的下界需要与其他变量的上界重新约束
+func (t *Typer) mergeCycle(cycle []*Variable, lhs *Variable, constrain func(SimpleType, SimpleType)) *Variable {
+	rep := cycle[0]
+	members := varSet{}
+	for _, v := range cycle {
+		members.Add(v)
+		if v.uid < rep.uid {
+			rep = v
+		}
+	}
+
+	collect := func(pol bool) []SimpleType {
+		seen := map[string]bool{}
+		var xs []SimpleType
+		for _, v := range cycle {
+			for _, b := range v.bounds(pol) {
+				b = representative(b)
+				if bv, ok := b.(*Variable); ok && members.Contains(bv) {
+					continue
+				}
+				if seen[b.hash()] {
+					continue
+				}
+				seen[b.hash()] = true
+				xs = append(xs, b)
+			}
+		}
+		if xs == nil {
+			xs = []SimpleType{}
+		}
+		return xs
+	}
+
+	// lhs 的下界 (已经包含沿链传播过来的下界) 之前只与 lhs 之后链上的上界约束过,
+	// 闭合后需要与所有上界约束
+	lowers := make([]SimpleType, 0, len(lhs.LowerBounds))
+	for _, lb := range lhs.LowerBounds {
+		lb = representative(lb)
+		if lv, ok := lb.(*Variable); ok && members.Contains(lv) {
+			continue
+		}
+		lowers = append(lowers, lb)
+	}
+
+	lbs, ubs := collect(true), collect(false)
+	log("[mergeCycle] %v => %v", cycle, rep)
+	if t.tracer != nil {
+		t.trace(&Event{Kind: EvMerge, Level: rep.level(), Lhs: fmt.Sprintf("%v", cycle), Rhs: rep.String()})
+	}
+	for _, v := range cycle {
+		if v != rep {
+			v._link = rep
+			v.LowerBounds = []SimpleType{rep}
+			v.UpperBounds = []SimpleType{rep}
+		}
+	}
+	rep.LowerBounds, rep.UpperBounds = lbs, ubs
+
+	for _, lb := range lowers {
+		for _, ub := range ubs {
+			constrain(lb, ub)
+		}
+	}
+	return rep
+}
@@ -0,0 +1,194 @@
+package typer
+
+// 否定类型
+//
+// ~N 表示除 N 之外的所有值, N 是不含类型变量的封闭类型, e.g. int ∧ ~0, ~(bool -> bool)
+//
+// 约束:
+//
+//	S <: ~N   当且仅当 S ∧ N = ⊥, 即 S 与 N 不相交
+//	~M <: ~N  当且仅当 N <: M
+//	~N <: S   S 不是否定类型时不成立 (~N 包含 N 之外所有类型的值)
+//
+// 不相交的判断是保守的: 无法确定时 (例如含有类型变量) 认为相交, 约束失败。
+//
+// 化简时否定类型与共现的基本类型一起规范化, 参见 normalizeNegs。
+//
+// 只支持封闭类型的否定, 不是 MLstruct 那样完整的布尔代数:
+// 否定不会分配到记录、函数等结构类型的分量上, 也不会把类型展开为析取范式 (DNF),
+// 与基本类型之外的类型共现的否定类型原样保留, e.g. {a: int} ∨ ~(bool -> bool) 不化简为 ~(bool -> bool)。
+
+// disjoint lhs ∧ rhs = ⊥
+func (t *Typer) disjoint(lhs, rhs SimpleType) bool {
+	switch l := lhs.(type) {
+	case *Intersection:
+		for _, it := range l.Types {
+			if t.disjoint(it, rhs) {
+				return true
+			}
+		}
+		return false
+	case *Union:
+		for _, it := range l.Types {
+			if !t.disjoint(it, rhs) {
+				return false
+			}
+		}
+		return true
+	case *Negation:
+		return t.trial(rhs, l.Neg)
+	}
+	switch r := rhs.(type) {
+	case *Intersection, *Union, *Negation:
+		return t.disjoint(r, lhs)
+	}
+
+	// 结构类型是否可比较由约束决定, e.g. 函数与 eq 不相交
+	for _, p := range []*Primitive{Equatable, Ordered} {
+		if _, ok := lhs.(*Primitive); !ok && rhs == p {
+			return !t.trial(lhs, p)
+		}
+		if _, ok := rhs.(*Primitive); !ok && lhs == p {
+			return !t.trial(rhs, p)
+		}
+	}
+
+	switch l := lhs.(type) {
+	case *Primitive:
+		if t.isClass(l) {
+			// 类的实例也是记录, 特质可以被任意类实现
+			switch r := rhs.(type) {
+			case *Record, *Extension:
+				return false
+			case *Primitive:
+				if t.isClass(r) && (t.classes[l].trait || t.classes[r].trait) {
+					return false
+				}
+			}
+		}
+		r, ok := rhs.(*Primitive)
+		return !ok || !t.isSubPrim(l, r) && !t.isSubPrim(r, l) && !t.hasCommonSub(l, r)
+	case *Function:
+		_, ok := rhs.(*Function)
+		return !ok
+	case *Ref:
+		_, ok := rhs.(*Ref)
+		return !ok
+	case *Tuple:
+		r, ok := rhs.(*Tuple)
+		if !ok {
+			return true
+		}
+		// 与记录相同, 元素个数不同的 tuple 有公共的子类型, 只比较公共的前缀
+		for i := 0; i < len(l.Elms) && i < len(r.Elms); i++ {
+			if t.disjoint(l.Elms[i], r.Elms[i]) {
+				return true
+			}
+		}
+		return false
+	case *Record:
+		if r, ok := rhs.(*Primitive); ok && t.isClass(r) {
+			return false
+		}
+		switch r := rhs.(type) {
+		case *Record:
+			// 两侧都可选的字段可以同时缺失, 不会因为字段的类型不相交而不相交
+			rm := r.fieldMap()
+			for _, fd := range l.Fields {
+				if rTy, ok := rm[fd.Name]; ok && !(fd.Optional && r.optional(fd.Name)) && t.disjoint(fd.Type, rTy) {
+					return true
+				}
+			}
+			return false
+		case *Extension:
+			return false
+		default:
+			return true
+		}
+	case *Extension:
+		if r, ok := rhs.(*Primitive); ok && t.isClass(r) {
+			return false
+		}
+		switch rhs.(type) {
+		case *Record, *Extension:
+			return false
+		default:
+			return true
+		}
+	case *Variant:
+		r, ok := rhs.(*Variant)
+		if !ok {
+			return true
+		}
+		rm := r.tagMap()
+		for _, tag := range l.Tags {
+			if _, ok := rm[tag.Name]; ok {
+				return false
+			}
+		}
+		return true
+	case *Constructor:
+		// 构造器可能展开为任意结构, 保守地认为相交
+		return false
+	default:
+		// 类型变量
+		return false
+	}
+}
+
+// normalizeNegs 规范化共现的基本类型与否定类型, 只处理下面几种吸收, 不做并集、交集的分配
+// 正极 (并集): p ∨ ~N = ~N (p 与 N 不相交), ~M ∨ ~N = ~N (N <: M)
+// 负极 (交集): p ∧ ~N = p (p 与 N 不相交), ~M ∧ ~N = ~M (N <: M)
+// 同一个 compactType 中的记录、函数等结构不参与规范化
+func (t *Typer) normalizeNegs(prims *sortedPrimSet, negs []*Negation, pol bool) (*sortedPrimSet, []*Negation) {
+	if len(negs) == 0 {
+		return prims, negs
+	}
+
+	// sub ~a <: ~b
+	sub := func(a, b *Negation) bool { return t.trial(b.Neg, a.Neg) }
+	var resNegs []*Negation
+	for _, m := range negs {
+		redundant := false
+		for _, n := range negs {
+			if m == n {
+				continue
+			}
+			// 等价的否定类型只保留 hash 较小的
+			if pol && sub(m, n) && (!sub(n, m) || m.hash() > n.hash()) ||
+				!pol && sub(n, m) && (!sub(m, n) || m.hash() > n.hash()) {
+				redundant = true
+				break
+			}
+		}
+		if !pol {
+			for _, p := range prims.Values() {
+				redundant = redundant || t.disjoint(p, m.Neg)
+			}
+		}
+		if !redundant {
+			resNegs = append(resNegs, m)
+		}
+	}
+
+	if !pol {
+		return prims, resNegs
+	}
+	res := unsortedPrimSet{}
+	changed := false
+	for _, p := range prims.Values() {
+		absorbed := false
+		for _, n := range resNegs {
+			absorbed = absorbed || t.disjoint(p, n.Neg)
+		}
+		if absorbed {
+			changed = true
+		} else {
+			res.Add(p)
+		}
+	}
+	if !changed {
+		return prims, resNegs
+	}
+	return res.ToSorted(), resNegs
+}
@@ -0,0 +1,135 @@
+package typer
+
+// 交集类型的重载
+//
+// builtins 可以声明为封闭类型的交集, e.g. add: (int -> int -> int) ∧ (float -> float -> float)
+//
+// 约束 (f1 ∧ ... ∧ fn) <: (a -> r) 时:
+//
+//	1. 对每个重载 fi = pi -> ri 试探约束 a <: pi, 成功的重载作为候选, 没有候选则报错
+//	2. 一般约束所有候选的参数, 返回值取交集, e.g. add 1 : (int -> int) ∧ (float -> float)
+//	3. a 是没有任何下界的类型变量时 (例如 lambda 参数), 约束所有候选会过度约束 a,
+//	   此时选择第一个候选作为默认重载 (类似 Haskell 的 defaulting), e.g. fun x -> add x 1 : int -> int
+//
+// 交集只出现在内置函数的签名中, 用户定义的 let 不会推导出交集, 也不会为了重载重新推导定义体
+//
+// 约束 (t1 ∧ ... ∧ tn) <: 非函数类型时, 任意一个成员满足即可。
+// 这不是 principal 的推导, 只是让同一个运算可以统一的作用在 int 和 float 上。
+
+// overload 约束 lhs <: rhs, rhs 不是类型变量
+func (t *Typer) overload(lhs *Intersection, rhs SimpleType, constrain func(SimpleType, SimpleType)) {
+	fun, isFun := rhs.(*Function)
+	if !isFun {
+		for _, it := range lhs.Types {
+			if t.trial(it, rhs) {
+				constrain(it, rhs)
+				return
+			}
+		}
+		panic(NewTypeError("cannot constrain %s <: %s", t.show(lhs), t.show(rhs)))
+	}
+
+	var alts []*Function
+	for _, it := range lhs.Types {
+		if f, ok := it.(*Function); ok && t.trial(fun.Lhs, f.Lhs) {
+			alts = append(alts, f)
+		}
+	}
+	if len(alts) == 0 {
+		panic(NewTypeError("no overload of %s matches %s", t.show(lhs), t.show(fun.Lhs)))
+	}
+	if len(alts) > 1 && !hasLowerBounds(fun.Lhs) {
+		alts = alts[:1]
+	}
+
+	results := make([]SimpleType, len(alts))
+	for i, f := range alts {
+		constrain(fun.Lhs, f.Lhs)
+		results[i] = f.Rhs
+	}
+	constrain(t.meet(results), fun.Rhs)
+}
+
+// trial 试探约束 lhs <: rhs 是否成立, 无论成功与否都撤销对边界的修改
+// 试探中不产生 tracer 事件, 撤销的修改不出现在 trace 中
+// 试探中 extrude 创建的变量撤销后不可达, 但不复用它们的 uid, 之后的 uid 与试探的次数无关
+func (t *Typer) trial(lhs, rhs SimpleType) (ok bool) {
+	saved, depth, tracer := t.trail, t.depth, t.tracer
+	var trail []func()
+	t.trail, t.tracer = &trail, nil
+	defer func() {
+		r := recover()
+		for i := len(trail) - 1; i >= 0; i-- {
+			trail[i]()
+		}
+		t.trail, t.depth, t.tracer = saved, depth, tracer
+		if r != nil {
+			switch r.(type) {
+			case TypeError, *TypeError:
+				ok = false
+			default:
+				panic(r)
+			}
+		}
+	}()
+	t.constrain(lhs, rhs)
+	return true
+}
+
+// hasLowerBounds 类型变量是否 (经由其他类型变量) 有非类型变量的下界, 非类型变量返回 true
+// ? 与任意重载相容, 不算作下界, e.g. host 1 + 1 选择第一个重载
+func hasLowerBounds(st SimpleType) bool {
+	v, ok := representative(st).(*Variable)
+	if !ok {
+		return st != Dyn
+	}
+	for _, tv := range closeOver(unsortedVarSet{}, unsortedVarSet{v.uid: v}, true) {
+		for _, b := range tv.LowerBounds {
+			if _, isVar := b.(*Variable); !isVar && b != Dyn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// meet 候选返回值的交集, 展开嵌套的交集并去重, 基本类型只保留子类型, e.g. int ∧ float = int
+func (t *Typer) meet(xs []SimpleType) SimpleType {
+	var flat []SimpleType
+	seen := map[string]bool{}
+	var add func(SimpleType)
+	add = func(st SimpleType) {
+		if it, ok := st.(*Intersection); ok {
+			for _, el := range it.Types {
+				add(el)
+			}
+			return
+		}
+		if !seen[st.hash()] {
+			seen[st.hash()] = true
+			flat = append(flat, st)
+		}
+	}
+	for _, x := range xs {
+		add(x)
+	}
+
+	var res []SimpleType
+	for _, x := range flat {
+		p, ok := x.(*Primitive)
+		redundant := false
+		for _, y := range flat {
+			if q, isPrim := y.(*Primitive); ok && isPrim && p != q && t.isSubPrim(q, p) {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			res = append(res, x)
+		}
+	}
+	if len(res) == 1 {
+		return res[0]
+	}
+	return Inters(res...)
+}
@@ -61,6 +61,33 @@ func children(st SimpleType) []SimpleType {
 			xs[i] = fd.Type
 		}
 		return xs
+	case *Extension:
+		xs := make([]SimpleType, 1+len(ty.Fields))
+		xs[0] = ty.Base
+		for i, fd := range ty.Fields {
+			xs[i+1] = fd.Type
+		}
+		return xs
+	case *Ref:
+		return []SimpleType{ty.Write, ty.Read}
+	case *Constructor:
+		xs := make([]SimpleType, 0, 2*len(ty.Args))
+		for _, arg := range ty.Args {
+			xs = append(xs, arg.In, arg.Out)
+		}
+		return xs
+	case *Variant:
+		xs := make([]SimpleType, len(ty.Tags))
+		for i, tag := range ty.Tags {
+			xs[i] = tag.Type
+		}
+		return xs
+	case *Intersection:
+		return ty.Types
+	case *Union:
+		return ty.Types
+	case *Negation:
+		return []SimpleType{ty.Neg}
 	case *Primitive:
 		return []SimpleType{}
 	default:
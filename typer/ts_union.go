@@ -0,0 +1,27 @@
+package typer
+
+// 并集类型
+//
+// 类型标注中可以写出封闭类型的并集, 与字面量类型一起描述配置的取值范围, e.g. {method: "GET" ∨ "POST"}
+// 成员不含类型变量, 与 Intersection 相同作为一个整体参与化简, 参见 typeExprIn
+//
+// 约束:
+//
+//	(A1 ∨ ... ∨ An) <: S   每个成员都满足 Ai <: S
+//	S <: (A1 ∨ ... ∨ An)   S 不是类型变量时, 选择第一个满足 S <: Ai 的成员, 没有则报错
+//	α <: (A1 ∨ ... ∨ An)   作为 α 的上界记录, α 的下界按上一条逐个检查
+//
+// 选择成员是保守的: S 含有类型变量时, 第一个满足的成员可能过度约束 S (与重载的 defaulting 相同)
+//
+// 字面量只有开启 WithSingletons 时才是单例类型, 否则 "GET" 的类型是 string, 不是 "GET" ∨ "POST" 的子类型
+
+// member 约束 lhs <: u, lhs 不是类型变量
+func (t *Typer) member(lhs SimpleType, u *Union, constrain func(SimpleType, SimpleType)) {
+	for _, it := range u.Types {
+		if t.trial(lhs, it) {
+			constrain(lhs, it)
+			return
+		}
+	}
+	panic(NewTypeError("cannot constrain %s <: %s", t.show(lhs), t.show(u)))
+}
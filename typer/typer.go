@@ -14,48 +14,208 @@ import (
 // compactTypeScheme = typer.canonicalizeType(SimpleType)
 // compactTypeScheme = typer.simplifyType(compactTypeScheme)
 // types.Type = coalesceCompactType(compactTypeScheme)
+//
+// 各阶段的中间结果可以通过 Pipeline 获取
 
 type Typer struct {
 	freshCount int
+	// 比较操作符内置类型的变量使用负数编号, 实例化时总会复制, 不影响推导结果中的编号
+	builtinCount int
+
+	// 约束求解时是否合并成环的等价类型变量, 参见 ts_mergevars.go
+	mergeVars bool
+
+	tracer Tracer
+	depth  int // 当前 constrain 递归深度, 用于 tracer
+
+	// 试探约束时记录边界修改的撤销操作, 参见 trial
+	trail *[]func()
+
+	simplification Simplification
+
+	// 字面量是否使用单例类型, 参见 WithSingletons
+	singletons bool
+
+	// 输出时是否折叠别名, 参见 WithAliasFolding
+	aliasFolding bool
+
+	// 类型声明, base 由 DeclarePrim 配置, 推导程序时在 base 的副本上声明源码中的类型, 参见 typeDecls
+	base *typeDecls
+	*typeDecls
+
+	// 正在推导的表达式与 ? 流入静态类型时需要的运行时转换, 参见 ts_dynamic.go
+	site  terms.Term
+	casts []Cast
 }
 
-func NewTyper() *Typer {
-	return &Typer{}
+// Option 配置 Typer
+type Option func(*Typer)
+
+// WithVarMerging 类型变量合并优化, 默认开启
+// 约束 α <: β 形成 α <: ... <: β <: α 环时, 环上变量等价, 合并为一个代表变量
+func WithVarMerging(on bool) Option {
+	return func(t *Typer) { t.mergeVars = on }
+}
+
+// Simplification 选择化简阶段, 用于比较结果、排查化简器问题或者用输出质量换取速度
+type Simplification struct {
+	// false: 直接使用 coalesceType, 跳过所有 compact 阶段, 以下选项无效
+	Canonicalize bool
+	// simplifyType 共现分析, 合并等价变量、移除单极性变量
+	CoOccurrence bool
+	// coalesceCompactType 对 compactType 做 hash-consing, 关闭后只有递归变量会生成递归类型
+	HashConsing bool
+}
+
+var (
+	SimplifyNone = Simplification{}
+	SimplifyFull = Simplification{Canonicalize: true, CoOccurrence: true, HashConsing: true}
+)
+
+// WithSimplification 默认 SimplifyFull
+func WithSimplification(s Simplification) Option {
+	return func(t *Typer) { t.simplification = s }
+}
+
+// WithSingletons 字面量使用单例类型, 默认关闭
+// e.g. "GET" : "GET" <: string, 42 : 42 <: int, 化简时与基类型共现的单例类型会被拓宽
+func WithSingletons(on bool) Option {
+	return func(t *Typer) { t.singletons = on }
+}
+
+// WithAliasFolding 输出推导结果时把与别名定义体匹配的结构类型折叠为别名, 默认关闭
+// 只作用于 InferTypes 与 Pipeline 的结果, 错误信息中的类型不折叠, 参见 typer_aliases.go
+func WithAliasFolding(on bool) Option {
+	return func(t *Typer) { t.aliasFolding = on }
+}
+
+func NewTyper(opts ...Option) *Typer {
+	t := &Typer{mergeVars: true, simplification: SimplifyFull}
+	t.base = &typeDecls{prims: primLattice{}, names: map[string]*Primitive{}, classes: map[*Primitive]*classInfo{}, ctors: map[string]*ctorInfo{}}
+	t.typeDecls = t.base
+	for _, p := range builtinPrims {
+		t.names[p.Name] = p
+	}
+	t.prims.add(Int, Float)
+	t.prims.add(Ordered, Equatable)
+	for _, p := range []*Primitive{Int, Float, String} {
+		t.prims.add(p, Ordered)
+	}
+	t.prims.add(Bool, Equatable)
+	t.prims.add(Unit, Equatable)
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 func (t *Typer) uuid() int { t.freshCount++; return t.freshCount - 1 }
 func (t *Typer) freshVar(lvl int) *Variable {
 	uid := t.uuid()
 	tv := Var(uid, lvl, []SimpleType{}, []SimpleType{})
+	if t.tracer != nil {
+		t.trace(&Event{Kind: EvFresh, Level: lvl, Lhs: tv.String()})
+	}
 	return tv
 }
 
 func (t *Typer) Builtins() *Ctx {
+	// 算术操作符重载 int 与 float, 参见 ts_overload.go
+	arith := Inters(Funx([]SimpleType{Int, Int}, Int), Funx([]SimpleType{Float, Float}, Float))
+	// 只有可比较的类型可以比较, 两个参数共享同一个类型变量, 参见 ts_comparable.go
+	// ∀𝛼 <: ord. 𝛼 → 𝛼 → bool
+	bounded := func(bound *Primitive) *PolymorphicType {
+		t.builtinCount--
+		tv := Var(t.builtinCount, 1, []SimpleType{}, []SimpleType{bound})
+		return PolyType(0, Funx([]SimpleType{tv, tv}, Bool))
+	}
+	logic := Funx([]SimpleType{Bool, Bool}, Bool)
 	return NewCtx(map[string]TypeScheme{
 		"true":  Bool,
 		"false": Bool,
 		"not":   Fun(Bool, Bool),
 		"succ":  Fun(Int, Int),
-		"add":   Fun(Int, Fun(Int, Int)),
+		"add":   Inters(Funx([]SimpleType{Int, Int}, Int), Funx([]SimpleType{Float, Float}, Float)),
 		// ∀𝛼, 𝛽. bool → 𝛼 → 𝛽 → 𝛼 ⊔ 𝛽
 		// ∀𝛼. bool → 𝛼 → 𝛼 → 𝛼
 		"if": func() *PolymorphicType {
 			tv := t.freshVar(1) // 类型变量的 level 要大于 polyType 的 level
 			return PolyType(0, Funx([]SimpleType{Bool, tv, tv}, tv))
 		}(),
+
+		// 操作符, 参见 parser/operator.go
+		"+":   arith,
+		"-":   arith,
+		"*":   arith,
+		"/":   arith,
+		"neg": Inters(Fun(Int, Int), Fun(Float, Float)),
+		"<":   bounded(Ordered),
+		"<=":  bounded(Ordered),
+		">":   bounded(Ordered),
+		">=":  bounded(Ordered),
+		"==":  bounded(Equatable),
+		"!=":  bounded(Equatable),
+		"&&":  logic,
+		"||":  logic,
+		// 前缀 ! 作用在字面量与操作符表达式上是逻辑非, 参见 parser/operator.go
+		"!": Fun(Bool, Bool),
+
+		// 字符串, 插值 "a ${x}" 展开为 concat 与 toString, 参见 parser/desugar.go
+		"concat":    Funx([]SimpleType{String, String}, String),
+		"length":    Fun(String, Int),
+		"substring": Funx([]SimpleType{String, Int, Int}, String),
+		// 可比较的值都可以转换为字符串, 函数不可以
+		"toString": Fun(Equatable, String),
 	})
 }
 
 func (t *Typer) inferTypes(pgrm *terms.Program, ctx *Ctx) (res []*PolymorphicType, err error) {
+	t.beginProgram()
 	defer func() {
 		if r := recover(); r != nil {
+			t.typeDecls = t.base
 			err = TypeErrorOf(r)
 		}
 	}()
+	for i, def := range pgrm.Defs {
+		for j := i + 1; j < len(pgrm.Defs) && def.Group != nil && pgrm.Defs[j].Group == def.Group; j++ {
+			if pgrm.Defs[j].Name == def.Name {
+				panic(NewTypeError("duplicate definition in let rec group: %s", def.Name))
+			}
+		}
+	}
+	t.declareTypes(pgrm.Types)
 	res = make([]*PolymorphicType, len(pgrm.Defs))
+	g := newDepGraph(pgrm.Defs, ctx)
+	for _, comp := range g.scc() {
+		if !g.isRecursive(comp) {
+			i := comp[0]
+			res[i] = t.typeLetRhs(pgrm.Defs[i], g.ctxFor(i, ctx, res), 0)
+			continue
+		}
+		// 成环的分量作为互递归组一起推导, 要求每个定义都是 rec
+		defs := make([]*terms.Declaration, len(comp))
+		nctx := ctx.clone()
+		for j, i := range comp {
+			if !pgrm.Defs[i].Rec {
+				panic(NewTypeError("cyclic definition through non-rec %s: %s", pgrm.Defs[i].Name, g.cycle(comp, i)))
+			}
+			defs[j] = pgrm.Defs[i]
+			for name, dep := range g.refs[i] {
+				if res[dep] != nil {
+					nctx.Add(name, res[dep])
+				}
+			}
+		}
+		for j, ty := range t.typeRecGroup(defs, nctx, 0) {
+			res[comp[j]] = ty
+		}
+	}
 	for i, def := range pgrm.Defs {
-		res[i] = t.typeLetRhs(def, ctx, 0)
-		ctx.Add(def.Name, res[i])
+		// 顶层表达式没有名字, 只有类型
+		if def.Name != "" {
+			ctx.Add(def.Name, res[i])
+		}
 	}
 	return
 }
@@ -80,10 +240,7 @@ func (t *Typer) InferTypes(pgrm *terms.Program, ctx *Ctx) (res []types.Type, err
 	}
 	res = make([]types.Type, len(tyv))
 	for i, poly := range tyv {
-		ty := poly.instantiate(t, 0)
-		cty := t.canonicalizeType(ty)
-		sty := t.simplifyType(cty)
-		res[i] = t.coalesceCompactType(sty)
+		res[i] = t.stages(poly.instantiate(t, 0)).Coalesced
 	}
 	return
 }
@@ -0,0 +1,306 @@
+package typer
+
+import (
+	"sort"
+
+	"github.com/goghcrow/simple-sub/terms"
+	"github.com/goghcrow/simple-sub/types"
+)
+
+// 类型别名
+//
+//	type Point = { x: int, y: int }
+//	type Stream[A] = { head: A, tail: unit -> Stream[A] }
+//
+// 别名即有定义体的构造器, 参见 typer_ctors.go, 可以用于标注, e.g. (s : Stream[int])
+//
+// 开启 WithAliasFolding 时, 输出时把与别名定义体匹配的结构类型折叠回别名, 递归类型的匹配是余归纳的 (coinductive):
+//
+//	'a -> {head: 'a, tail: unit -> 'b} as 'b  折叠为  'a -> Stream['a]
+//
+// 匹配要求结构相同, 除了可以把正极的 ⊥ 与负极的 ⊤ 匹配任意类型,
+// 即只把类型替换为其父类型 (正极) 或子类型 (负极), e.g. {head: int, tail: ⊤ -> 'b} as 'b 折叠为 Stream[int]
+// 所有参数都绑定到类型才折叠, 多个别名匹配时取名字最小的。
+// 定义体是函数类型的别名不折叠, 否则 int -> unit 会被折叠为 Sink[int], Sink[int] -> unit 会被折叠为 Sink[Sink[int]]。
+// 定义体只是参数或者基本类型、类、字面量类型的别名也不折叠, 否则 type Id[A] = A 匹配任意类型, type MyInt = int 替换所有的 int。
+// 定义体是并集的别名按成员的顺序匹配, e.g. type Method = "GET" ∨ "POST"。
+
+type aliasKey struct {
+	name string
+	ty   types.Type
+}
+
+type aliasFolder struct {
+	*Typer
+	names []string
+	// 递归类型变量 -> 递归类型
+	rec map[*types.TypeVariable]*types.RecursiveType
+	// 正在匹配的 (别名, 类型) 及其参数绑定, 再次遇到时假设匹配成功
+	assumed map[aliasKey]map[string]types.Type
+	// 正在折叠参数绑定的 (别名, 类型), 绑定再次折叠为同一个别名时不折叠, 避免无限展开
+	folding map[aliasKey]bool
+}
+
+// foldAliases 把类型中与别名定义体匹配的部分折叠为别名
+func (t *Typer) foldAliases(ty types.Type) types.Type {
+	var names []string
+	for name, info := range t.ctors {
+		if info.foldable(t) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ty
+	}
+	sort.Strings(names)
+	f := &aliasFolder{
+		Typer:   t,
+		names:   names,
+		rec:     map[*types.TypeVariable]*types.RecursiveType{},
+		assumed: map[aliasKey]map[string]types.Type{},
+		folding: map[aliasKey]bool{},
+	}
+	return f.fold(ty, true)
+}
+
+// foldable 定义体是否可以用于折叠, 参见文件开头
+func (info *ctorInfo) foldable(t *Typer) bool {
+	switch body := info.body.(type) {
+	case nil, *terms.TyFun, *terms.TyLit:
+		return false
+	case *terms.TyName:
+		return t.ctors[body.Name] != nil && !info.isParam(body.Name)
+	default:
+		return true
+	}
+}
+
+// isParam name 是否是参数
+func (info *ctorInfo) isParam(name string) bool {
+	for _, p := range info.params {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *aliasFolder) fold(ty types.Type, pol bool) types.Type {
+	if r, ok := ty.(*types.RecursiveType); ok {
+		f.rec[r.UV] = r
+	}
+	for _, name := range f.names {
+		if ct := f.foldAs(name, ty, pol); ct != nil {
+			return ct
+		}
+	}
+
+	switch ty := ty.(type) {
+	case *types.UnionType:
+		return types.Union(f.fold(ty.Lhs, pol), f.fold(ty.Rhs, pol))
+	case *types.InterType:
+		return types.Inter(f.fold(ty.Lhs, pol), f.fold(ty.Rhs, pol))
+	case *types.FunctionType:
+		return types.Func(f.fold(ty.Lhs, !pol), f.fold(ty.Rhs, pol))
+	case *types.TupleType:
+		xs := make([]types.Type, len(ty.Elms))
+		for i, el := range ty.Elms {
+			xs[i] = f.fold(el, pol)
+		}
+		return types.Tuple(xs)
+	case *types.RecordType:
+		return types.Record(f.foldFields(ty.Fields, pol))
+	case *types.ExtensionType:
+		return types.Extension(f.fold(ty.Base, pol), f.foldFields(ty.Fields, pol), ty.Removed)
+	case *types.VariantType:
+		return types.Variant(f.foldFields(ty.Tags, pol))
+	case *types.RefType:
+		return types.Ref(f.fold(ty.In, !pol), f.fold(ty.Out, pol))
+	case *types.CtorType:
+		xs := make([]types.CtorArg, len(ty.Args))
+		for i, arg := range ty.Args {
+			if arg.In != nil {
+				xs[i].In = f.fold(arg.In, !pol)
+			}
+			if arg.Out != nil {
+				xs[i].Out = f.fold(arg.Out, pol)
+			}
+		}
+		return types.Ctor(ty.Name, xs)
+	case *types.NegationType:
+		return types.Neg(f.fold(ty.Neg, !pol))
+	case *types.RecursiveType:
+		// 递归的部分整体折叠为别名后不再需要递归类型
+		body := f.fold(ty.Body, pol)
+		if !types.Occurs(ty.UV, body) {
+			return body
+		}
+		return types.Recur(ty.UV, body)
+	default:
+		return ty
+	}
+}
+
+func (f *aliasFolder) foldFields(fds []types.Field, pol bool) []types.Field {
+	xs := make([]types.Field, len(fds))
+	for i, fd := range fds {
+		xs[i] = types.Field{Name: fd.Name, Type: f.fold(fd.Type, pol), Optional: fd.Optional}
+	}
+	return xs
+}
+
+// foldAs 尝试把 ty 折叠为别名 name, 参数的绑定继续折叠
+func (f *aliasFolder) foldAs(name string, ty types.Type, pol bool) types.Type {
+	info := f.ctors[name]
+	key := aliasKey{name, f.resolve(ty)}
+	if f.folding[key] {
+		return nil
+	}
+	env := f.matchAlias(name, ty, pol)
+	if env == nil {
+		return nil
+	}
+	f.folding[key] = true
+	defer delete(f.folding, key)
+	xs := make([]types.CtorArg, len(info.params))
+	for i, p := range info.params {
+		if env[p] == nil {
+			return nil
+		}
+		if info.variance[i]&contravariant != 0 {
+			xs[i].In = f.fold(env[p], !pol)
+		}
+		if info.variance[i]&covariant != 0 {
+			xs[i].Out = f.fold(env[p], pol)
+		}
+	}
+	return types.Ctor(name, xs)
+}
+
+// resolve 递归类型变量解析为其递归类型
+func (f *aliasFolder) resolve(ty types.Type) types.Type {
+	if tv, ok := ty.(*types.TypeVariable); ok && f.rec[tv] != nil {
+		return f.rec[tv]
+	}
+	return ty
+}
+
+// unwrap 展开递归类型
+func (f *aliasFolder) unwrap(ty types.Type) types.Type {
+	ty = f.resolve(ty)
+	for {
+		r, ok := ty.(*types.RecursiveType)
+		if !ok {
+			return ty
+		}
+		f.rec[r.UV] = r
+		ty = r.Body
+	}
+}
+
+// matchAlias 匹配别名的定义体, 返回参数的绑定 (可能不完整), 不匹配返回 nil
+func (f *aliasFolder) matchAlias(name string, ty types.Type, pol bool) map[string]types.Type {
+	info := f.ctors[name]
+	if info.body == nil {
+		return nil
+	}
+	ty = f.resolve(ty)
+	key := aliasKey{name, ty}
+	if env, ok := f.assumed[key]; ok {
+		return env
+	}
+	env := map[string]types.Type{}
+	f.assumed[key] = env
+	defer delete(f.assumed, key)
+	if !f.match(info.body, ty, pol, info, env) {
+		return nil
+	}
+	return env
+}
+
+func (f *aliasFolder) match(te terms.TypeExpr, ty types.Type, pol bool, info *ctorInfo, env map[string]types.Type) bool {
+	if app, ok := te.(*terms.TyApp); ok {
+		return f.matchApp(app.Name, app.Args, ty, pol, info, env)
+	}
+	ty = f.unwrap(ty)
+	switch ty.(type) {
+	case *types.BotType:
+		if pol {
+			return true
+		}
+	case *types.TopType:
+		if !pol {
+			return true
+		}
+	}
+
+	switch te := te.(type) {
+	case *terms.TyName:
+		for _, p := range info.params {
+			if p == te.Name {
+				if b := env[p]; b != nil {
+					return types.Equal(b, ty)
+				}
+				env[p] = ty
+				return true
+			}
+		}
+		if f.ctors[te.Name] != nil {
+			return f.matchApp(te.Name, nil, ty, pol, info, env)
+		}
+		p, ok := ty.(*types.PrimitiveType)
+		return ok && p.Name == te.Name
+	case *terms.TyFun:
+		fn, ok := ty.(*types.FunctionType)
+		return ok && f.match(te.Lhs, fn.Lhs, !pol, info, env) && f.match(te.Rhs, fn.Rhs, pol, info, env)
+	case *terms.TyRecord:
+		rcd, ok := ty.(*types.RecordType)
+		if !ok || len(rcd.Fields) != len(te.Fields) {
+			return false
+		}
+		fm := map[string]types.Field{}
+		for _, fd := range rcd.Fields {
+			fm[fd.Name] = fd
+		}
+		for _, fd := range te.Fields {
+			rfd, ok := fm[fd.Name]
+			if !ok || rfd.Optional != fd.Optional || !f.match(fd.Type, rfd.Type, pol, info, env) {
+				return false
+			}
+		}
+		return true
+	case *terms.TyLit:
+		p, ok := ty.(*types.PrimitiveType)
+		return ok && p.Name == litType(te.Lit).Name
+	case *terms.TyUnion:
+		u, ok := ty.(*types.UnionType)
+		return ok && f.match(te.Lhs, u.Lhs, pol, info, env) && f.match(te.Rhs, u.Rhs, pol, info, env)
+	default:
+		panic("unreached")
+	}
+}
+
+// matchApp 匹配 G[args], ty 可以是已经输出为 G 的构造器, 或者匹配 G 的定义体
+func (f *aliasFolder) matchApp(name string, args []terms.TypeExpr, ty types.Type, pol bool, info *ctorInfo, env map[string]types.Type) bool {
+	if ct, ok := f.resolve(ty).(*types.CtorType); ok && ct.Name == name {
+		for i, arg := range ct.Args {
+			if arg.In != nil && !f.match(args[i], arg.In, !pol, info, env) {
+				return false
+			}
+			if arg.Out != nil && !f.match(args[i], arg.Out, pol, info, env) {
+				return false
+			}
+		}
+		return true
+	}
+	genv := f.matchAlias(name, ty, pol)
+	if genv == nil {
+		return false
+	}
+	for i, p := range f.ctors[name].params {
+		if b := genv[p]; b != nil && !f.match(args[i], b, pol, info, env) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	decls := `
+	type Point = { x: int, y: int }
+	type Pair[A] = { fst: A, snd: A }
+	type Stream[A] = { head: A, tail: unit -> Stream[A] }
+	let rec mk = fun x -> { head: x, tail: fun u -> mk x }
+	`
+	testTypes(t, freshEnv(nil, WithAliasFolding(true)), withDecls(decls, []typeCase{
+		{"let f = mk", "'a -> Stream['a]"},
+		{"let s = mk 1", "Stream[int]"},
+		{"let s = (mk 1).tail ()", "Stream[int]"},
+		{"let p = { x: 1, y: 2 }", "Point"},
+		{"let p = { x: 1, y: true }", "{x: int, y: bool}"},
+		{"let f = fun p -> p.x", "{x: 'a} -> 'a"},
+		{"let p = { fst: 1, snd: 2 }", "Pair[int]"},
+		{"let p = { fst: 1, snd: true }", "{fst: int, snd: bool}"},
+		{"let f = fun x -> { fst: x, snd: x }", "'a -> Pair['a]"},
+		{"let p = { fst: { x: 1, y: 2 }, snd: { x: 3, y: 4 } }", "Pair[Point]"},
+		// 标注
+		{"let p = ({ x: 1, y: 2 } : Point)", "Point"},
+		{"let s = (mk 1 : Stream[int])", "Stream[int]"},
+		{"let f = fun s -> (s : Stream[int]).head", "Stream[int] -> int"},
+		{"let f = fun p -> (p : Point).x", "Point -> int"},
+		// 定义体只是参数或者基本类型的别名不折叠
+		{"type Id[A] = A\nlet x = 1", "int"},
+		{"type Id[A] = A\nlet f = fun x -> (x : Id[int])", "Id[int] -> Id[int]"},
+		{"type MyInt = int\nlet f = fun y -> y + 1", "int -> int"},
+		{"type P = Point\nlet p = { x: 1, y: 2 }", "P"},
+	}))
+
+	testErrors(t, freshEnv(nil), []typeCase{
+		{decls + "let p = ({ x: 1 } : Point)", "missing field: y in {x: int}"},
+		{decls + "let p = ({ x: 1, y: true } : Point)", "cannot constrain bool <: int"},
+		{decls + "let s = (mk true : Stream[int])", "cannot constrain bool <: int"},
+		{decls + "let s = (mk 1 : Stream)", "Stream expects 1 type arguments, got 0"},
+		{decls + "let s = (1 : Queue[int])", "undefined type constructor: Queue"},
+	})
+}
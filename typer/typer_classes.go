@@ -0,0 +1,178 @@
+package typer
+
+import (
+	"sort"
+
+	"github.com/goghcrow/simple-sub/terms"
+)
+
+// 名义类型: 类与特质
+//
+//	trait Showable
+//	class Point: Base, Showable { x: int, y: int }
+//
+// 类与特质表示为基本类型 (名义标签), 继承关系加入基本类型的子类型关系,
+// 因此标签与基本类型一样出现在并集与交集中, 由 compactType 的 prim 跟踪, e.g. Circle ∨ Point, Showable ∧ {id: 'a}
+//
+// 标签携带声明的字段 (包括继承的字段), 约束 Point <: {x: 'a} 时按声明的字段类型约束。
+// 类名即构造函数 Point: {id: int, x: int, y: int} -> Point, e.g. Point { id: 1, x: 1, y: 2 }
+// 类的声明属于所在的程序, 参见 typeDecls
+
+type classInfo struct {
+	trait  bool
+	fields []field // 自身声明的字段
+}
+
+func (t *Typer) isClass(p *Primitive) bool { return t.classes[p] != nil }
+
+// declareClasses 先登记所有名字, 允许按任意顺序声明父类型
+func (t *Typer) declareClasses(decls []*terms.ClassDecl) {
+	for _, d := range decls {
+		for _, b := range builtinPrims {
+			if d.Name == b.Name {
+				panic(NewTypeError("cannot redeclare built-in type as class: %s", d.Name))
+			}
+		}
+		p := t.prim(d.Name)
+		if t.isClass(p) {
+			panic(NewTypeError("duplicate class: %s", d.Name))
+		}
+		t.classes[p] = &classInfo{trait: d.Trait}
+	}
+	for _, d := range decls {
+		p := t.names[d.Name]
+		for _, name := range d.Parents {
+			sup := t.names[name]
+			info := t.classes[sup]
+			if info == nil {
+				panic(NewTypeError("undefined class or trait: %s", name))
+			}
+			if d.Trait && !info.trait {
+				panic(NewTypeError("trait %s cannot extend class %s", d.Name, name))
+			}
+			if p == sup || t.prims.isSub(sup, p) {
+				panic(NewTypeError("cyclic inheritance: %s <: %s <: %s", p, sup, p))
+			}
+			t.prims.add(p, sup)
+		}
+		info := t.classes[p]
+		for _, fd := range d.Fields {
+			info.fields = append(info.fields, field{Name: fd.Name, Type: t.typeExpr(fd.Type)})
+		}
+	}
+	// 覆盖的字段必须是父类型字段的子类型
+	for _, d := range decls {
+		p := t.names[d.Name]
+		for _, fd := range t.classes[p].fields {
+			for _, sup := range t.superClasses(p) {
+				for _, sfd := range t.classes[sup].fields {
+					if sfd.Name == fd.Name && !t.trial(fd.Type, sfd.Type) {
+						panic(NewTypeError("field %s: %s in %s is not a subtype of %s in %s",
+							fd.Name, t.show(fd.Type), p, t.show(sfd.Type), sup))
+					}
+				}
+			}
+		}
+	}
+}
+
+// typeExpr 声明中的类型表达式
+func (t *Typer) typeExpr(te terms.TypeExpr) SimpleType {
+	return t.typeExprIn(te, nil, true)
+}
+
+// typeExprIn env 为构造器参数的替换, 参数在正极取协变分量, 在负极取逆变分量
+func (t *Typer) typeExprIn(te terms.TypeExpr, env map[string]ctorArg, pol bool) SimpleType {
+	switch ty := te.(type) {
+	case *terms.TyName:
+		if arg, ok := env[ty.Name]; ok {
+			if pol {
+				return arg.Out
+			}
+			return arg.In
+		}
+		// 没有参数的别名, e.g. type Point = {x: int, y: int}
+		if t.ctors[ty.Name] != nil {
+			t.ctor(ty.Name, 0)
+			return CtorX(ty.Name, nil)
+		}
+		p := t.names[ty.Name]
+		if p == nil {
+			panic(NewTypeError("undefined type: %s", ty.Name))
+		}
+		return p
+	case *terms.TyFun:
+		return Fun(t.typeExprIn(ty.Lhs, env, !pol), t.typeExprIn(ty.Rhs, env, pol))
+	case *terms.TyRecord:
+		xs := make([]field, len(ty.Fields))
+		for i, fd := range ty.Fields {
+			xs[i] = field{fd.Name, t.typeExprIn(fd.Type, env, pol), fd.Optional}
+		}
+		return Rcd(xs)
+	case *terms.TyApp:
+		t.ctor(ty.Name, len(ty.Args))
+		xs := make([]ctorArg, len(ty.Args))
+		for i, arg := range ty.Args {
+			xs[i] = ctorArg{t.typeExprIn(arg, env, !pol), t.typeExprIn(arg, env, pol)}
+		}
+		return CtorX(ty.Name, xs)
+	case *terms.TyLit:
+		return litType(ty.Lit)
+	case *terms.TyUnion:
+		// 成员不能含有构造器的参数, 参见 ts_union.go
+		u := Unions(t.typeExprIn(ty.Lhs, env, pol), t.typeExprIn(ty.Rhs, env, pol))
+		if len(getVars(u)) != 0 {
+			panic(NewTypeError("union type must be closed: %s", ty))
+		}
+		return u
+	default:
+		panic("unreached")
+	}
+}
+
+// superClasses p 的所有 (传递) 父类型中的类与特质, 按名字排序
+func (t *Typer) superClasses(p *Primitive) []*Primitive {
+	var xs []*Primitive
+	for sup := range t.prims[p] {
+		if t.isClass(sup) {
+			xs = append(xs, sup)
+		}
+	}
+	sort.Slice(xs, func(i, j int) bool { return xs[i].Name < xs[j].Name })
+	return xs
+}
+
+// classFields 名义类型的所有字段 (包括继承的), 按名字排序
+// 同名字段取自身与所有父类型声明的交集
+func (t *Typer) classFields(p *Primitive) []field {
+	decls := map[string][]SimpleType{}
+	for _, c := range append([]*Primitive{p}, t.superClasses(p)...) {
+		for _, fd := range t.classes[c].fields {
+			decls[fd.Name] = append(decls[fd.Name], fd.Type)
+		}
+	}
+	names := make([]string, 0, len(decls))
+	for name := range decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	xs := make([]field, len(names))
+	for i, name := range names {
+		xs[i] = field{Name: name, Type: t.meet(decls[name])}
+	}
+	return xs
+}
+
+// construct 类名作为构造函数, e.g. Point { id: 1, x: 1, y: 2 } : Point
+// 不带参数时是构造函数自身, 特质不能构造
+func (t *Typer) construct(p *Primitive, tm *terms.Tag, ctx *Ctx, lvl int) SimpleType {
+	if t.classes[p].trait {
+		panic(NewTypeError("cannot instantiate trait: %s", p))
+	}
+	ctor := Fun(Rcd(t.classFields(p)), p)
+	if tm.Arg == nil {
+		return ctor
+	}
+	t.constrain(t.typeTerm(tm.Arg, ctx, lvl), ctor.Lhs)
+	return p
+}
@@ -0,0 +1,68 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestClasses(t *testing.T) {
+	decls := `
+	trait Showable
+	class Base { id: int }
+	class Point: Base, Showable { x: int, y: int }
+	class Circle: Base { r: float }
+	let p = Point { id: 1, x: 1, y: 2 }
+	`
+	classesCtx := func(typer *Typer) *Ctx {
+		ctx := typer.Builtins()
+		ctx.Add("describe", Fun(typer.Prim("Showable"), String))
+		return ctx
+	}
+	testTypes(t, freshEnv(classesCtx), withDecls(decls, []typeCase{
+		{"let x = p", "Point"},
+		{"let x = Circle", "{id: int, r: float} -> Circle"},
+		{"let x = p.x", "int"},
+		{"let x = (Circle { id: 2, r: 1 }).r", "float"},
+		{"let f = fun b -> if b then p else Circle { id: 2, r: 1.0 }", "bool -> Circle ∨ Point"},
+		{"let f = fun b -> (if b then p else Circle { id: 2, r: 1.0 }).id", "bool -> int"},
+		// Point ∨ Base = Base
+		{"let f = fun b -> fun c -> if b then p else c.base", "bool -> {base: 'a} -> 'a ∨ Point"},
+		{"let f = fun s -> describe s", "Showable -> string"},
+		{"let x = describe p", "string"},
+		{"let f = fun s -> let d = describe s in s.id", "Showable ∧ {id: 'a} -> 'a"},
+		{"let f = fun c -> if true then c else p", "'a -> 'a ∨ Point"},
+	}))
+
+	testErrors(t, freshEnv(classesCtx), []typeCase{
+		{decls + "let x = describe (Circle { id: 1, r: 1.0 })", "cannot constrain Circle <: Showable"},
+		{decls + "let x = (Circle { id: 1, r: 1.0 }).x", "missing field: x in Circle"},
+		{decls + "let x = Point { x: 1, y: 2 }", "missing field: id in {x: int, y: int}"},
+		{decls + "let x = Showable", "cannot instantiate trait: Showable"},
+		{"class A: B\nclass B: A", "cyclic inheritance: B <: A <: B"},
+		{"class A: C", "undefined class or trait: C"},
+		{"class A\ntrait T: A", "trait T cannot extend class A"},
+		{"class A\nclass A", "duplicate class: A"},
+		{"class int", "cannot redeclare built-in type as class: int"},
+		{"trait bool", "cannot redeclare built-in type as class: bool"},
+		{"class A { id: int }\nclass B: A { id: bool }", "field id: bool in B is not a subtype of int in A"},
+		{"class A { id: uuid }", "undefined type: uuid"},
+		{"let x = (1 : nat)", "undefined type: nat"},
+	})
+}
+
+// 类的声明属于所在的程序, 同一个 Typer 可以重复推导
+func TestClassesPerProgram(t *testing.T) {
+	typer := NewTyper()
+	pgrm := "class A { id: int }\nlet a = A { id: 1 }"
+	for i := 0; i < 2; i++ {
+		if _, err := typer.InferTypes(parsePgrm(pgrm), typer.Builtins()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewPipeline(typer, typer.Builtins()).Program(parsePgrm(pgrm)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := typer.InferTypes(parsePgrm("let f = fun a -> (a : A)"), typer.Builtins())
+	if err == nil || err.Error() != "undefined type: A" {
+		t.Errorf("expect undefined type: A actual %v", err)
+	}
+}
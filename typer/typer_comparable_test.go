@@ -0,0 +1,40 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestComparable(t *testing.T) {
+	testTypes(t, freshEnv(nil), []typeCase{
+		{"let x = 1 == 2", "bool"},
+		{"let x = 1 < 2.5", "bool"},
+		{"let x = \"a\" < \"b\"", "bool"},
+		{"let x = (1, \"a\") < (2, \"b\")", "bool"},
+		{"let x = {a: 1, b: true} == {a: 2, b: false}", "bool"},
+		{"let x = Some 1 == None", "bool"},
+		{"let x = ref (fun x -> x) == ref (fun x -> x)", "bool"},
+		{"let f = fun x -> x == 1", "eq -> bool"},
+		{"let f = fun x -> x < 1", "ord -> bool"},
+		// 与可比较的基本类型共现时不显示
+		{"let f = fun x -> x + 1 == 1", "int -> bool"},
+		{"let f = fun x -> if x == 1 then x else 2", "'a ∧ eq -> 'a ∨ int"},
+		{"let f = fun x -> (x == x, x < x)", "ord -> (bool, bool)"},
+		{"let f = fun r -> r.a == r.b", "{a: eq, b: eq} -> bool"},
+		{"class Point { x: int }\nlet x = Point {x: 1} == Point {x: 2}", "bool"},
+		{"type nat <: int\nlet f = fun x -> (x : nat) < 1", "nat -> bool"},
+	})
+
+	testErrors(t, freshEnv(nil), []typeCase{
+		{"let x = (fun x -> x) == (fun x -> x)", "cannot constrain 'a -> 'a <: eq"},
+		{"let x = (1, fun x -> x) == (1, fun x -> x)", "cannot constrain 'a -> 'a <: eq"},
+		{"let x = {f: fun x -> x} == {f: fun x -> x}", "cannot constrain 'a -> 'a <: eq"},
+		{"let x = true < false", "cannot constrain bool <: ord"},
+		{"let x = {a: 1} < {a: 2}", "cannot constrain {a: int} <: ord"},
+		{"let f = fun g -> g == g\nlet x = f succ", "cannot constrain int -> int <: eq"},
+		// 两个参数共享同一个类型变量
+		{"let x = 1 < \"a\"", "cannot compare int with string"},
+		{"let x = 1 == true", "cannot compare int with bool"},
+		{"let x = (1, 2) < (\"a\", 2)", "cannot compare (int, int) with (string, int)"},
+		{"let f = fun x y -> x < y\nlet z = f 1 \"a\"", "cannot compare int with string"},
+	})
+}
@@ -0,0 +1,240 @@
+package typer
+
+import (
+	"fmt"
+
+	"github.com/goghcrow/simple-sub/terms"
+)
+
+// 参数化的类型构造器
+//
+//	type Box[A] = { value: A }
+//	type Sink[A] = A -> unit
+//	type Cell[A] = { get: unit -> A, set: A -> unit }
+//	type List[out A]
+//
+// 构造器的应用 F[T1, ..., Tn] 表示为 Constructor, 与 Ref 一样每个参数保存逆变分量 In 与协变分量 Out。
+// 约束 F[A] <: F[B] 时按参数的型变分解:
+//
+//	协变 A.Out <: B.Out, 逆变 B.In <: A.In, 不变两者都约束
+//
+// 有定义体的构造器是结构类型的别名, 与其他类型约束时展开定义体, e.g. {value: int} <: Box[int]
+// 没有定义体的是抽象构造器, 只能与同名构造器约束。
+//
+// 型变可以标注 (in/out), 否则从定义体推导: 参数只出现在正极为协变, 只出现在负极为逆变, 否则不变。
+// 未使用的参数视为协变, 没有标注的抽象构造器的参数视为不变。
+
+type variance int
+
+const (
+	covariant     variance = 1
+	contravariant variance = 2
+	invariant              = covariant | contravariant
+)
+
+func (v variance) flip() variance { return v&covariant<<1 | v&contravariant>>1 }
+
+func (v variance) String() string {
+	switch v {
+	case covariant:
+		return "out"
+	case contravariant:
+		return "in"
+	default:
+		return "invariant"
+	}
+}
+
+type ctorInfo struct {
+	params   []string
+	variance []variance
+	body     terms.TypeExpr // nil 为抽象构造器
+}
+
+// ctor 查找构造器并检查参数个数
+func (t *Typer) ctor(name string, arity int) *ctorInfo {
+	info := t.ctors[name]
+	if info == nil {
+		panic(NewTypeError("undefined type constructor: %s", name))
+	}
+	if len(info.params) != arity {
+		panic(NewTypeError("%s expects %d type arguments, got %d", name, len(info.params), arity))
+	}
+	return info
+}
+
+// variances 构造器每个参数的型变
+func (t *Typer) variances(name string, arity int) []variance {
+	return t.ctor(name, arity).variance
+}
+
+func (t *Typer) declareCtors(decls []*terms.CtorDecl) {
+	for _, d := range decls {
+		if t.ctors[d.Name] != nil {
+			panic(NewTypeError("duplicate type constructor: %s", d.Name))
+		}
+		info := &ctorInfo{body: d.Body, variance: make([]variance, len(d.Params))}
+		for i, p := range d.Params {
+			info.params = append(info.params, p.Name)
+			if d.Body == nil {
+				info.variance[i] = annotatedVariance(p, invariant)
+			}
+		}
+		t.ctors[d.Name] = info
+	}
+
+	// 从 0 (未出现) 开始单调增长直到不动点, 递归的定义体引用自身时使用当前的推导结果
+	for changed := true; changed; {
+		changed = false
+		for _, d := range decls {
+			info := t.ctors[d.Name]
+			if d.Body == nil {
+				continue
+			}
+			occ := map[string]variance{}
+			t.occurrences(d.Body, info, covariant, occ)
+			for i, p := range info.params {
+				if v := info.variance[i] | occ[p]; v != info.variance[i] {
+					info.variance[i] = v
+					changed = true
+				}
+			}
+		}
+	}
+
+	for _, d := range decls {
+		info := t.ctors[d.Name]
+		if d.Body == nil {
+			continue
+		}
+		for i, p := range d.Params {
+			inferred := info.variance[i]
+			declared := annotatedVariance(p, inferred)
+			if declared == 0 {
+				declared = covariant
+			}
+			if inferred&^declared != 0 {
+				panic(NewTypeError("type parameter %s of %s is declared %s but occurs in %s position",
+					p.Name, d.Name, declared, positionOf(inferred&^declared)))
+			}
+			info.variance[i] = declared
+		}
+	}
+}
+
+func annotatedVariance(p terms.TyParam, dflt variance) variance {
+	switch p.Variance {
+	case "in":
+		return contravariant
+	case "out":
+		return covariant
+	default:
+		return dflt
+	}
+}
+
+func positionOf(v variance) string {
+	if v&covariant != 0 {
+		return "covariant"
+	}
+	return "contravariant"
+}
+
+// occurrences 收集定义体中参数出现的位置, pos 为当前位置的型变
+func (t *Typer) occurrences(te terms.TypeExpr, info *ctorInfo, pos variance, occ map[string]variance) {
+	switch ty := te.(type) {
+	case *terms.TyName:
+		for _, p := range info.params {
+			if p == ty.Name {
+				occ[p] |= pos
+			}
+		}
+	case *terms.TyFun:
+		t.occurrences(ty.Lhs, info, pos.flip(), occ)
+		t.occurrences(ty.Rhs, info, pos, occ)
+	case *terms.TyRecord:
+		for _, fd := range ty.Fields {
+			t.occurrences(fd.Type, info, pos, occ)
+		}
+	case *terms.TyUnion:
+		// 并集的成员是封闭类型, 不能出现参数, 参见 ts_union.go
+		inner := map[string]variance{}
+		t.occurrences(ty.Lhs, info, pos, inner)
+		t.occurrences(ty.Rhs, info, pos, inner)
+		if len(inner) != 0 {
+			panic(NewTypeError("union type must be closed: %s", ty))
+		}
+	case *terms.TyLit:
+	case *terms.TyApp:
+		for i, arg := range ty.Args {
+			// 出现在 G 的参数中, 位置的型变与 G 的参数的型变复合
+			var v variance
+			switch g := t.ctor(ty.Name, len(ty.Args)).variance[i]; g {
+			case covariant:
+				v = pos
+			case contravariant:
+				v = pos.flip()
+			case invariant:
+				v = invariant
+			}
+			if v != 0 {
+				t.occurrences(arg, info, v, occ)
+			}
+		}
+	default:
+		panic("unreached")
+	}
+}
+
+// unfold 展开构造器的定义体, 参数在正极替换为协变分量, 在负极替换为逆变分量
+func (t *Typer) unfold(c *Constructor, pol bool) SimpleType {
+	info := t.ctor(c.Name, len(c.Args))
+	env := map[string]ctorArg{}
+	for i, p := range info.params {
+		env[p] = c.Args[i]
+	}
+	return t.typeExprIn(info.body, env, pol)
+}
+
+func (t *Typer) hasBody(st SimpleType) bool {
+	c, ok := st.(*Constructor)
+	return ok && t.ctor(c.Name, len(c.Args)).body != nil
+}
+
+// constrainCtor 约束同名构造器 lhs <: rhs
+func (t *Typer) constrainCtor(lhs, rhs *Constructor, constrain func(SimpleType, SimpleType)) {
+	for i, v := range t.variances(lhs.Name, len(lhs.Args)) {
+		if v&covariant != 0 {
+			constrain(lhs.Args[i].Out, rhs.Args[i].Out)
+		}
+		if v&contravariant != 0 {
+			constrain(rhs.Args[i].In, lhs.Args[i].In)
+		}
+	}
+}
+
+// compactCtor 按型变转换构造器的参数, 未使用的分量为空, 不参与共现分析
+func (t *Typer) compactCtor(c *Constructor, pol bool, do func(SimpleType, bool) *compactType) *compactCtor {
+	args := make([]*compactFun, len(c.Args))
+	for i, v := range t.variances(c.Name, len(c.Args)) {
+		args[i] = &compactFun{emptyCompactType(), emptyCompactType()}
+		if v&contravariant != 0 {
+			args[i].lhs = do(c.Args[i].In, !pol)
+		}
+		if v&covariant != 0 {
+			args[i].rhs = do(c.Args[i].Out, pol)
+		}
+	}
+	return &compactCtor{c.Name, args}
+}
+
+func (c *compactCtor) stringify(f func(*compactType) string) string {
+	s := c.name + "["
+	for i, arg := range c.args {
+		if i > 0 {
+			s += "; "
+		}
+		s += fmt.Sprintf("in %s, out %s", f(arg.lhs), f(arg.rhs))
+	}
+	return s + "]"
+}
@@ -0,0 +1,98 @@
+package typer
+
+import (
+	"testing"
+)
+
+func ctorsCtx(typer *Typer) *Ctx {
+	ctx := typer.Builtins()
+	poly := func(f func(a *Variable) SimpleType) *PolymorphicType {
+		return PolyType(0, f(typer.freshVar(1)))
+	}
+	ctx.Add("box", poly(func(a *Variable) SimpleType { return Fun(a, Ctor("Box", a)) }))
+	ctx.Add("cons", poly(func(a *Variable) SimpleType { return Funx([]SimpleType{a, Ctor("List", a)}, Ctor("List", a)) }))
+	ctx.Add("nil", poly(func(a *Variable) SimpleType { return Ctor("List", a) }))
+	ctx.Add("cell", poly(func(a *Variable) SimpleType { return Fun(a, Ctor("Cell", a)) }))
+	ctx.Add("feed", poly(func(a *Variable) SimpleType { return Funx([]SimpleType{Ctor("Sink", a), a}, Unit) }))
+	ctx.Add("drain", Ctor("Sink", Int))
+	ctx.Add("openBox", Fun(Ctor("Box", Int), Int))
+	return ctx
+}
+
+func TestCtors(t *testing.T) {
+	decls := `
+	type Box[A] = { value: A }
+	type Sink[A] = A -> unit
+	type Cell[A] = { get: unit -> A, set: A -> unit }
+	type List[out A]
+	`
+	testTypes(t, freshEnv(ctorsCtx), withDecls(decls, []typeCase{
+		{"let x = box 1", "Box[int]"},
+		{"let x = (box 1).value", "int"},
+		{"let f = fun b -> if b then box 1 else box true", "bool -> Box[bool ∨ int]"},
+		{"let x = cons 1 (cons true nil)", "List[bool ∨ int]"},
+		{"let f = fun x -> cons x nil", "'a -> List['a]"},
+		// 结构类型与构造器互相展开
+		{"let x = openBox { value: 1 }", "int"},
+		{"let x = openBox (box 1)", "int"},
+		{"let f = fun b -> b.value", "{value: 'a} -> 'a"},
+		// Sink 逆变
+		{"let x = feed drain 1", "unit"},
+		{"let f = fun s -> feed s 1", "Sink[int] -> unit"},
+		// Cell 不变, 读写分量分别合并
+		{"let x = cell 1", "Cell[in 'a, out 'a ∨ int]"},
+		{"let f = fun b -> if b then cell 1 else cell true", "bool -> Cell[in 'a, out 'a ∨ bool ∨ int]"},
+	}))
+
+	for _, tt := range []struct {
+		decls    string
+		expected string
+	}{
+		{"type F[A] = A", "out"},
+		{"type F[A] = A -> unit", "in"},
+		{"type F[A] = A -> A", "invariant"},
+		{"type F[A] = int", "out"},
+		{"type F[in A] = int", "in"},
+		{"type F[A]", "invariant"},
+		{"type S[A] = A -> unit\ntype F[A] = S[S[A]]", "out"},
+		{"type F[A] = { head: A, tail: unit -> F[A] }", "out"},
+		{"type F[A] = { get: A, sink: F[A] -> unit }", "invariant"},
+	} {
+		t.Run(tt.decls, func(t *testing.T) {
+			typer := NewTyper()
+			_, err := typer.inferTypes(parsePgrm(tt.decls), typer.Builtins())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res := typer.variances("F", 1)[0].String(); res != tt.expected {
+				t.Errorf("expect %s actual %s", tt.expected, res)
+			}
+		})
+	}
+
+	testErrors(t, freshEnv(ctorsCtx), []typeCase{
+		{decls + "let x = openBox (box true)", "cannot constrain bool <: int"},
+		{decls + "let x = feed drain true", "cannot constrain bool <: int"},
+		{decls + "let x = openBox { value: true }", "cannot constrain bool <: int"},
+		{decls + "let x = openBox (cons 1 nil)", "cannot constrain List['a ∨ int] <: {value: int}"},
+		{"type Sink[out A] = A -> unit", "type parameter A of Sink is declared out but occurs in contravariant position"},
+		{"type F[A] = G[A]", "undefined type constructor: G"},
+		{"type F[A] = A\ntype G[A] = F[A, A]", "F expects 1 type arguments, got 2"},
+		{"type F[A] = A\ntype F[B] = B", "duplicate type constructor: F"},
+	})
+}
+
+// 构造器的声明属于所在的程序, 同一个 Typer 可以重复推导
+func TestCtorsPerProgram(t *testing.T) {
+	typer := NewTyper()
+	pgrm := "type G[A] = {v: A}\nlet g = ({v: 1} : G[int])"
+	for i := 0; i < 2; i++ {
+		if _, err := typer.InferTypes(parsePgrm(pgrm), typer.Builtins()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := typer.InferTypes(parsePgrm("let g = ({v: 1} : G[int])"), typer.Builtins())
+	if err == nil || err.Error() != "undefined type constructor: G" {
+		t.Errorf("expect undefined type constructor: G actual %v", err)
+	}
+}
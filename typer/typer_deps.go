@@ -0,0 +1,304 @@
+package typer
+
+import (
+	"strings"
+
+	"github.com/goghcrow/simple-sub/terms"
+)
+
+// 顶层定义的依赖分析
+//
+// 顶层定义可以按任意顺序出现, 引用的解析规则 (保持顺序定义时的遮蔽语义):
+//
+//	1. 所在 let rec 组的成员, 或 let rec 定义自身
+//	2. 之前最近的同名定义
+//	3. ctx 中的名字 (builtins), 即之后的定义不遮蔽 builtins, e.g. let f = succ 1 中的 succ 是 builtin
+//	4. 之后最近的同名定义
+//
+// 之后用 Tarjan 算法求强连通分量, 按依赖顺序推导, 同一个分量中的定义作为互递归组一起推导。
+// 分量中存在非 rec 定义 (包括引用自身的非 rec 定义) 时报错。
+
+// depGraph 顶层定义的依赖图, 节点为 Program.Defs 的下标
+type depGraph struct {
+	defs []*terms.Declaration
+	// 推导程序之前的 ctx, 参见 resolve
+	ctx *Ctx
+	// edges[i] 定义 i 依赖的定义, 按出现顺序去重
+	edges [][]int
+	// refs[i] 定义 i 引用的名字 -> 解析到的定义
+	refs []map[string]int
+}
+
+func newDepGraph(defs []*terms.Declaration, ctx *Ctx) *depGraph {
+	g := &depGraph{
+		defs:  defs,
+		ctx:   ctx,
+		edges: make([][]int, len(defs)),
+		refs:  make([]map[string]int, len(defs)),
+	}
+	for i, def := range defs {
+		g.refs[i] = map[string]int{}
+		for _, name := range freeVars(def.Rhs) {
+			j, ok := g.resolve(i, name)
+			if !ok {
+				continue
+			}
+			g.refs[i][name] = j
+			g.edges[i] = append(g.edges[i], j)
+		}
+	}
+	return g
+}
+
+func (g *depGraph) resolve(i int, name string) (int, bool) {
+	def := g.defs[i]
+	if def.Group != nil {
+		for j := i; j >= 0 && g.defs[j].Group == def.Group; j-- {
+			if g.defs[j].Name == name {
+				return j, true
+			}
+		}
+		for j := i + 1; j < len(g.defs) && g.defs[j].Group == def.Group; j++ {
+			if g.defs[j].Name == name {
+				return j, true
+			}
+		}
+	} else if def.Rec && def.Name == name {
+		return i, true
+	}
+	for j := i - 1; j >= 0; j-- {
+		if g.defs[j].Name == name {
+			return j, true
+		}
+	}
+	if _, ok := g.ctx.env[name]; ok {
+		return 0, false
+	}
+	for j := i + 1; j < len(g.defs); j++ {
+		if g.defs[j].Name == name {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// scc Tarjan 强连通分量, 依赖在前; 依赖都在之前的定义保持原有顺序
+func (g *depGraph) scc() [][]int {
+	var (
+		index   = 0
+		indices = make([]int, len(g.defs))
+		lowLink = make([]int, len(g.defs))
+		onStack = make([]bool, len(g.defs))
+		stack   []int
+		res     [][]int
+	)
+	for i := range indices {
+		indices[i] = -1
+	}
+
+	var connect func(int)
+	connect = func(v int) {
+		indices[v] = index
+		lowLink[v] = index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range g.edges[v] {
+			if indices[w] < 0 {
+				connect(w)
+				if lowLink[w] < lowLink[v] {
+					lowLink[v] = lowLink[w]
+				}
+			} else if onStack[w] && indices[w] < lowLink[v] {
+				lowLink[v] = indices[w]
+			}
+		}
+
+		if lowLink[v] == indices[v] {
+			var comp []int
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				comp = append(comp, w)
+				if w == v {
+					break
+				}
+			}
+			// 分量内按定义顺序排列
+			for x := 1; x < len(comp); x++ {
+				for y := x; y > 0 && comp[y] < comp[y-1]; y-- {
+					comp[y], comp[y-1] = comp[y-1], comp[y]
+				}
+			}
+			res = append(res, comp)
+		}
+	}
+
+	for i := range g.defs {
+		if indices[i] < 0 {
+			connect(i)
+		}
+	}
+	return res
+}
+
+// isRecursive 分量是否成环 (多个定义, 或者引用自身)
+func (g *depGraph) isRecursive(comp []int) bool {
+	if len(comp) > 1 {
+		return true
+	}
+	for _, w := range g.edges[comp[0]] {
+		if w == comp[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// cycle 分量内从 from 出发回到 from 的一条路径, e.g. a -> b -> a
+func (g *depGraph) cycle(comp []int, from int) string {
+	in := map[int]bool{}
+	for _, v := range comp {
+		in[v] = true
+	}
+	visited := map[int]bool{}
+	var path []int
+	var dfs func(int) bool
+	dfs = func(v int) bool {
+		path = append(path, v)
+		for _, w := range g.edges[v] {
+			if w == from {
+				path = append(path, w)
+				return true
+			}
+			if in[w] && !visited[w] {
+				visited[w] = true
+				if dfs(w) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	dfs(from)
+	xs := make([]string, len(path))
+	for i, v := range path {
+		xs[i] = g.defs[v].Name
+	}
+	return strings.Join(xs, " -> ")
+}
+
+// ctxFor 定义 i 推导时的 ctx, 引用的名字绑定为解析到的定义的类型
+func (g *depGraph) ctxFor(i int, ctx *Ctx, res []*PolymorphicType) *Ctx {
+	nctx := ctx.clone()
+	for name, j := range g.refs[i] {
+		if res[j] != nil {
+			nctx.Add(name, res[j])
+		}
+	}
+	return nctx
+}
+
+// freeVars term 中的自由变量, 按出现顺序去重
+func freeVars(term terms.Term) []string {
+	var res []string
+	seen := map[string]bool{}
+	var do func(terms.Term, map[string]bool)
+	do = func(term terms.Term, bound map[string]bool) {
+		bind := func(names ...string) map[string]bool {
+			nb := make(map[string]bool, len(bound)+len(names))
+			for k := range bound {
+				nb[k] = true
+			}
+			for _, name := range names {
+				nb[name] = true
+			}
+			return nb
+		}
+		switch tm := term.(type) {
+		case *terms.LiteralBool, *terms.LiteralInt, *terms.LiteralFloat, *terms.LiteralString, *terms.LiteralUnit:
+		case *terms.Variable:
+			if !bound[tm.Name] && !seen[tm.Name] {
+				seen[tm.Name] = true
+				res = append(res, tm.Name)
+			}
+		case *terms.Lambda:
+			do(tm.Rhs, bind(tm.Name))
+		case *terms.Application:
+			do(tm.Lhs, bound)
+			do(tm.Rhs, bound)
+		case *terms.Selection:
+			do(tm.Recv, bound)
+		case *terms.Projection:
+			do(tm.Recv, bound)
+		case *terms.Coalesce:
+			do(tm.Recv, bound)
+			do(tm.Default, bound)
+		case *terms.Tuple:
+			for _, el := range tm.Elms {
+				do(el, bound)
+			}
+		case *terms.List:
+			for _, el := range tm.Elms {
+				do(el, bound)
+			}
+		case *terms.Record:
+			for _, fd := range tm.Fields {
+				do(fd.Term, bound)
+			}
+		case *terms.Extend:
+			do(tm.Recv, bound)
+			for _, fd := range tm.Fields {
+				do(fd.Term, bound)
+			}
+		case *terms.Restrict:
+			do(tm.Recv, bound)
+		case *terms.LetDefine:
+			if tm.Rec {
+				do(tm.Rhs, bind(tm.Name))
+			} else {
+				do(tm.Rhs, bound)
+			}
+			do(tm.Body, bind(tm.Name))
+		case *terms.LetGroup:
+			names := make([]string, len(tm.Defs))
+			for i, def := range tm.Defs {
+				names[i] = def.Name
+			}
+			nb := bind(names...)
+			for _, def := range tm.Defs {
+				do(def.Rhs, nb)
+			}
+			do(tm.Body, nb)
+		case *terms.Tag:
+			if tm.Arg != nil {
+				do(tm.Arg, bound)
+			}
+		case *terms.Case:
+			do(tm.Scrut, bound)
+			for _, arm := range tm.Arms {
+				if arm.Name != "" {
+					do(arm.Body, bind(arm.Name))
+				} else {
+					do(arm.Body, bound)
+				}
+			}
+		case *terms.NewRef:
+			do(tm.Init, bound)
+		case *terms.Deref:
+			do(tm.Ref, bound)
+		case *terms.Assign:
+			do(tm.Ref, bound)
+			do(tm.Rhs, bound)
+		case *terms.Ascribe:
+			do(tm.Term, bound)
+		default:
+			panic("unreached")
+		}
+	}
+	do(term, map[string]bool{})
+	return res
+}
@@ -0,0 +1,56 @@
+package typer
+
+import (
+	"testing"
+
+	"github.com/goghcrow/simple-sub/terms"
+)
+
+func TestShowDot(t *testing.T) {
+	typer := NewTyper()
+	// fun x -> x 42
+	term := terms.Lam("x", terms.App(terms.Var("x"), terms.Int(42)))
+	st := typer.inferType(term, typer.Builtins())
+
+	expect := `digraph bounds {
+	node [fontname=monospace];
+	v1 [label="α1\nlevel 0"];
+	v2 [label="α2\nlevel 0"];
+	root [shape=point];
+	s0 [shape=box, label="(α1 -> α2)"];
+	s0 -> v1 [style=dashed, arrowhead=none];
+	s0 -> v2 [style=dashed, arrowhead=none];
+	root -> s0;
+	s1 [shape=box, label="(int -> α2)"];
+	s1 -> v2 [style=dashed, arrowhead=none];
+	v1 -> s1 [color=red];
+}
+`
+	if actual := ShowDot(st); actual != expect {
+		t.Errorf("expect %s actual %s", expect, actual)
+	}
+}
+
+func TestShowCanonicalDot(t *testing.T) {
+	typer := NewTyper()
+	pgrm := parsePgrm("let rec recursive_monster = fun x -> { thing: x, self: recursive_monster x }")
+	tyv, err := typer.inferTypes(pgrm, typer.Builtins())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := tyv[0].instantiate(typer, 0)
+
+	expect := `digraph compact {
+	node [fontname=monospace];
+	term [shape=box, label="‹α4, ‹α5› -> ‹{self: ‹α7›, thing: ‹α5›}››"];
+	term -> v7 [style=dashed];
+	v7 [label="α7"];
+	r7 [shape=box, label="‹α6, {self: ‹α7›, thing: ‹α5›}›"];
+	v7 -> r7 [label="="];
+	r7 -> v7 [style=dashed];
+}
+`
+	if actual := typer.ShowCanonicalDot(st); actual != expect {
+		t.Errorf("expect %s actual %s", expect, actual)
+	}
+}
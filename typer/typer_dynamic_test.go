@@ -0,0 +1,88 @@
+package typer
+
+import (
+	"testing"
+
+	"github.com/goghcrow/simple-sub/terms"
+)
+
+func TestDynamic(t *testing.T) {
+	for _, tt := range []struct {
+		pgrm     string
+		expected string
+		casts    []string
+	}{
+		{"let f = host", "? -> ?", nil},
+		{"let f = (1 : ?)", "?", nil},
+		{"let f = (host 1).name", "?", nil},
+		{"let f = let r = ref (host 1) in !r", "?", nil},
+		// ? 在正极展示动态值流经的位置
+		{"let f = fun x -> host x", "⊤ -> ?", nil},
+		{"let f = fun x -> if x then host x else 1", "bool -> ? ∨ int", nil},
+		// α <: ? 不记录为上界
+		{"let f = fun x -> let y = (x : ?) in x + 1", "int -> int", nil},
+		// ? 流入基本类型时需要运行时转换
+		{"let f = host 1 + 1", "int", []string{"+ host 1: ? => int"}},
+		{"let f = if host 1 then 1 else 2", "int", []string{"if host 1: ? => bool"}},
+		{"let f = (host : int -> int)", "int -> int", []string{"(host : int -> int): ? => int"}},
+		// 函数交给宿主, 宿主传入的参数在函数体中检查, 归咎于调用宿主的位置
+		{"let f = apply (fun x -> x + 1)", "unit", []string{"apply (fun x -> + x 1): ? => int"}},
+		// ? <: α 与 α 之后的任何上界相容, 不会引入类型错误
+		{"let f = let y = host 1 in (y + 1, not y)", "(int, bool)", []string{"+ y: ? => int", "not y: ? => bool"}},
+		// 源码相同的位置各自需要转换
+		{"let f = (host 1 + 1, host 1 + 1)", "(int, int)", []string{"+ host 1: ? => int", "+ host 1: ? => int"}},
+	} {
+		t.Run(tt.pgrm, func(t *testing.T) {
+			typer := NewTyper()
+			res, err := showLast(typer, dynamicBuiltins(typer), tt.pgrm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tt.expected {
+				t.Errorf("expect %s actual %s", tt.expected, res)
+			}
+			casts := typer.Casts()
+			if len(casts) != len(tt.casts) {
+				t.Fatalf("expect %v actual %v", tt.casts, casts)
+			}
+			for i, c := range casts {
+				if c.String() != tt.casts[i] {
+					t.Errorf("expect %s actual %s", tt.casts[i], c)
+				}
+			}
+		})
+	}
+
+	// ? 只与动态的部分相容, 静态的部分照常检查
+	testErrors(t, freshEnv(dynamicBuiltins), []typeCase{
+		{"let f = host 1 + true", "cannot constrain bool <: int"},
+		{"let f = (host : int -> int) true", "cannot constrain bool <: int"},
+	})
+}
+
+func TestCastsPerProgram(t *testing.T) {
+	typer := NewTyper()
+	pgrm := parsePgrm("let a = host 1 + 1")
+	if _, err := typer.inferTypes(pgrm, dynamicBuiltins(typer)); err != nil {
+		t.Fatal(err)
+	}
+	// 转换插入在 + 的参数 host 1 所在的应用处
+	site := pgrm.Defs[0].Rhs.(*terms.Application).Lhs
+	if casts := typer.CastsAt(site); len(casts) != 1 || casts[0].String() != "+ host 1: ? => int" {
+		t.Errorf("expect [+ host 1: ? => int] actual %v", casts)
+	}
+	if _, err := typer.inferTypes(parsePgrm("let b = 2"), dynamicBuiltins(typer)); err != nil {
+		t.Fatal(err)
+	}
+	if casts := typer.Casts(); len(casts) != 0 {
+		t.Errorf("expect [] actual %v", casts)
+	}
+}
+
+// dynamicBuiltins 无类型的宿主函数
+func dynamicBuiltins(typer *Typer) *Ctx {
+	ctx := typer.Builtins()
+	ctx.Add("host", Fun(Dyn, Dyn))
+	ctx.Add("apply", Fun(Dyn, Unit))
+	return ctx
+}
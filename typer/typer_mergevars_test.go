@@ -0,0 +1,53 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestVarMerging(t *testing.T) {
+	for _, tt := range []struct {
+		pgrm     string
+		where    string // 开启合并后的边界
+		expected string
+	}{
+		{
+			"let rec f = fun x -> if true then x else f x",
+			"α8 :> (α9 -> α10) <: (α9 -> α10), α9 <: α10",
+			"'a -> 'a",
+		},
+		{
+			"let rec loop = fun x -> if true then x else loop (if true then x else loop x)",
+			"α13 :> (α14 -> α14) <: (α14 -> α14) & (α14 -> α14)",
+			"'a -> 'a",
+		},
+	} {
+		t.Run(tt.pgrm, func(t *testing.T) {
+			infer := func(typer *Typer) (SimpleType, string) {
+				pgrm := parsePgrm(tt.pgrm)
+				tyv, err := typer.inferTypes(pgrm, typer.Builtins())
+				if err != nil {
+					t.Fatal(err)
+				}
+				st := tyv[0].instantiate(typer, 0)
+				cty := typer.simplifyType(typer.canonicalizeType(st))
+				return st, typer.coalesceCompactType(cty).Show()
+			}
+
+			merged, res := infer(NewTyper())
+			if where := ShowBounds(merged); where != tt.where {
+				t.Errorf("where: expect %s actual %s", tt.where, where)
+			}
+			if res != tt.expected {
+				t.Errorf("expect %s actual %s", tt.expected, res)
+			}
+
+			unmerged, res := infer(NewTyper(WithVarMerging(false)))
+			if ShowBounds(unmerged) == tt.where {
+				t.Errorf("expect bounds changed by merging")
+			}
+			if res != tt.expected {
+				t.Errorf("unmerged: expect %s actual %s", tt.expected, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestNegation(t *testing.T) {
+	typer := NewTyper(WithSingletons(true))
+	zero := Lit("0", Int)
+	ctx := typer.Builtins()
+	ctx.Add("nonZero", Fun(Int, Inters(Int, Neg(zero))))
+	ctx.Add("recip", Fun(Inters(Int, Neg(zero)), Float))
+	ctx.Add("nz", Neg(zero))
+	// 除函数之外的任意值
+	ctx.Add("dump", Fun(Neg(Fun(Bool, Bool)), String))
+	ctx.Add("notFn", Neg(Fun(Bool, Bool)))
+	ctx.Add("notY", Fun(Neg(typer.Prim("y")), Bool))
+	ctx.Add("anX", typer.Prim("x"))
+
+	testTypes(t, sharedEnv(typer, ctx), []typeCase{
+		{"let x = nonZero 5", "int ∧ ~0"},
+		{"let f = fun x -> recip x", "int ∧ ~0 -> float"},
+		{"let x = recip 5", "float"},
+		{"let x = recip (nonZero 0)", "float"},
+		{"let x = (fun y -> y) nz", "~0"},
+		{"let x = dump { a: 1 }", "string"},
+		{"let f = fun x -> if true then x else nz", "'a -> 'a ∨ ~0"},
+		// bool ∧ ~0 = bool
+		{"let f = fun x -> if x then 1.0 else recip x", "bool ∧ int -> float"},
+		// true ∨ ~0 = ~0
+		{"let x = if true then true else nz", "~0"},
+		{"type x\ntype y\nlet b = notY anX", "bool"},
+		// 只吸收基本类型, 结构类型与否定类型共现时原样保留
+		{"let x = if true then { a: 1 } else notFn", "{a: 1} ∨ ~(bool -> bool)"},
+	})
+
+	testErrors(t, sharedEnv(typer, ctx), []typeCase{
+		{"let x = recip 0", "cannot constrain 0 <: ~0"},
+		{"let x = succ nz", "cannot constrain ~0 <: int"},
+		{"let x = dump succ", "cannot constrain int -> int <: ~(bool -> bool)"},
+		{"let x = dump (fun y -> y)", "cannot constrain 'a -> 'a <: ~(bool -> bool)"},
+		// a 同时是 x 与 y 的子类型, x 与 y 相交
+		{"type a <: x\ntype a <: y\nlet b = notY anX", "cannot constrain x <: ~y"},
+	})
+}
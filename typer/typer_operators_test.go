@@ -0,0 +1,42 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestOperators(t *testing.T) {
+	testTypes(t, freshEnv(nil), []typeCase{
+		{"let x = 1 + 2 * 3", "int"},
+		{"let x = 1 + 2.5", "float"},
+		{"let x = 1 - -2", "int"},
+		{"let x = -(1.5 / 2.0)", "float"},
+		{"let f = fun x -> x * 2", "int -> int"},
+		{"let x = 1 < 2 && not (2.5 >= 3)", "bool"},
+		{"let f = fun x -> fun y -> x == y", "eq -> eq -> bool"},
+		{"let x = { a: 1 } != { a: 2 }", "bool"},
+		{"let f = fun a -> fun b -> a || b && true", "bool -> bool -> bool"},
+		{"let rec fact = fun n -> if n <= 1 then 1 else n * fact (n - 1)", "int -> int"},
+		{"let r = ref 1\nlet x = r := !r + 1", "unit"},
+		{"let r = ref (ref 1)\nlet x = !!r", "int"},
+		// ! 作用在字面量与操作符表达式上是逻辑非, 其余是解引用
+		{"let x = !(1 < 2) || !true", "bool"},
+		{"let r = ref true\nlet x = !(!r && true)", "bool"},
+		{"let f = fun x -> !x", "Ref[in ⊥, out 'a] -> 'a"},
+		{"let f = fun x -> not x", "bool -> bool"},
+		// 自定义操作符与片段
+		{"infixl 8.5 ++\nlet (++) = fun a -> fun b -> { fst: a, snd: b }\nlet x = 1 * 2 ++ true", "{fst: int, snd: bool}"},
+		{"infixl 1 |>\nlet (|>) = fun x -> fun f -> f x\nlet x = 1 |> (+ 1) |> (2 <)", "bool"},
+		{"let f = (- 1)", "int"},
+		{"let f = (-)", "(int -> int -> int) ∧ (float -> float -> float)"},
+		{"let f = (1.5 *)", "float -> float"},
+	})
+
+	testErrors(t, freshEnv(nil), []typeCase{
+		{"let x = 1 + true", "no overload of (int -> int) ∧ (float -> float) matches bool"},
+		{"let x = (1 < 2) < 3", "cannot constrain bool <: ord"},
+		{"let x = !1", "cannot constrain int <: bool"},
+		{"let r = ref true\nlet x = !!r", "cannot constrain bool <: Ref[in 'a, out 'b]"},
+		{"let x = not 1", "cannot constrain int <: bool"},
+		{"let x = 1 && true", "cannot constrain int <: bool"},
+	})
+}
@@ -0,0 +1,43 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestOptionalFields(t *testing.T) {
+	testTypes(t, freshEnv(nil, WithAliasFolding(true)), []typeCase{
+		{"let x = ({} : {port?: int})", "{port?: int}"},
+		{"let x = ({port: 1} : {port?: int})", "{port?: int}"},
+		{"let x = {}.port ?? 8080", "int"},
+		{"let x = {port: \"p\"}.port ?? 8080", "int ∨ string"},
+		{"let f = fun r -> r.port ?? 8080", "{port?: 'a} -> 'a ∨ int"},
+		{"let f = fun r -> (r : {port?: int}).port ?? 1", "{port?: int} -> int"},
+		{"let f = fun r -> (r.port ?? 8080) + r.host", "{host: int, port?: int} -> int"},
+		// 负极合并: 必需的字段覆盖可选的字段
+		{"let f = fun r -> (r.port ?? 1, r.port)", "{port: 'a} -> ('a ∨ int, 'a)"},
+		// 正极合并: 任意一侧可选则可选
+		{"let f = fun r -> if true then (r : {port?: int}) else {port: true}", "{port?: int} -> {port?: bool ∨ int}"},
+		{"let f = fun r -> if true then (r : {port?: int}) else {port: 1, host: true}", "{port?: int} -> {port?: int}"},
+		// 删除的字段满足可选字段
+		{"let f = fun r -> (r \\ port).port ?? true", "{} -> bool"},
+		{"let f = fun r -> {r with port: 1}.port ?? true", "{} -> bool ∨ int"},
+		{"class Point { x: int, z: bool }\nlet p = (Point {x: 1, z: true} : {x: int, z?: bool})", "{x: int, z?: bool}"},
+		// 缺少字段的记录字面量确实没有该字段
+		{"let f = fun r -> (r.port ?? 1) + 1\nlet x = f {host: \"h\"}", "int"},
+		// 别名按可选性匹配
+		{"type Config = {port?: int, host: string}\nlet c = ({host: \"localhost\"} : {port?: int, host: string})", "Config"},
+		{"type Config = {port?: int, host: string}\nlet f = fun c -> (c : {port: int, host: string})", "{host: string, port: int} -> {host: string, port: int}"},
+	})
+
+	testErrors(t, freshEnv(nil), []typeCase{
+		{"let x = ({port: true} : {port?: int})", "cannot constrain bool <: int"},
+		{"let x = (({} : {port?: int}) : {port: int})", "optional field: port in {port?: int}"},
+		{"let x = ({} : {port?: int}).port", "optional field: port in {port?: int}"},
+		// 宽度子类型遗忘的字段类型是 ⊤, 而不是缺失
+		{"let f = fun r -> (r.port ?? 1) + 1\nlet x = f ({port: \"s\"} : {})", "cannot constrain ⊤ <: float"},
+		{"let x = (({port: \"s\"} : {}) : {port?: int})", "cannot constrain ⊤ <: int"},
+		// 子类的实例可能有同名的字段
+		{"class Point { x: int }\nlet p = (Point {x: 1} : {x: int, z?: bool})", "cannot constrain ⊤ <: bool"},
+		{"let x = ({port: 1} : {port?: int}).port ?? true + 1", "no overload of (int -> int -> int) ∧ (float -> float -> float) matches bool"},
+	})
+}
@@ -0,0 +1,29 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestOverload(t *testing.T) {
+	typer := NewTyper()
+	ctx := typer.Builtins()
+	ctx.Add("show", Inters(Fun(Int, String), Fun(Bool, String)))
+
+	testTypes(t, sharedEnv(typer, ctx), []typeCase{
+		{"let x = add 1 2", "int"},
+		{"let x = add 1.5 2.5", "float"},
+		{"let x = add 1 2.5", "float"},
+		{"let x = succ (add 1 2)", "int"},
+		{"let inc = add 1", "(int -> int) ∧ (float -> float)"},
+		// 参数没有下界时使用第一个重载
+		{"let f = fun x -> add x 1", "int -> int"},
+		{"let f = fun x -> add (succ x) 2.5", "int -> float"},
+		{"let f = fun b -> if b then show 1 else show true", "bool -> string"},
+	})
+
+	testErrors(t, sharedEnv(typer, ctx), []typeCase{
+		{"let x = add true", "no overload of (int -> int -> int) ∧ (float -> float -> float) matches bool"},
+		{"let x = succ (add 1 2.5)", "cannot constrain float <: int"},
+		{"let x = show 1.5", "no overload of (int -> string) ∧ (bool -> string) matches float"},
+	})
+}
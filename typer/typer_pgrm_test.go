@@ -94,6 +94,143 @@ func TestPgrm(t *testing.T) {
 				"int",
 			},
 		},
+		{
+			"variants",
+			`
+	let some = Some 1
+	let none = None
+	let opt = fun b -> if b then Some 1 else None
+	let get = fun o -> case o of { Some x -> x, None -> 0 }
+	let res = get (opt true)
+	let map = fun f o -> case o of { Some x -> Some (f x), None -> None }
+	let rec eval = fun e -> case e of { Lit n -> n, Add p -> add (eval p.lhs) (eval p.rhs) }
+	let res = eval (Add { lhs: Lit 1, rhs: Lit 2 })
+`,
+			[]string{
+				"[Some int]",
+				"[None]",
+				"bool -> [None | Some int]",
+				"[None | Some 'a] -> 'a ∨ int",
+				"int",
+				"('a -> 'b) -> [None | Some 'a] -> [None | Some 'b]",
+				"[Add {lhs: 'a, rhs: 'a} | Lit int] as 'a -> int",
+				"int",
+			},
+		},
+		{
+			"references",
+			`
+	let r = ref 1
+	let get = fun r -> !r
+	let set = fun r -> fun v -> r := v
+	let x = !r
+	let u = r := 2
+	let mk = fun x -> ref x
+	let counter = let c = ref 0 in fun u -> c := succ !c
+	let rid = ref (fun x -> x)
+	let a = rid := (fun x -> succ x)
+`,
+			[]string{
+				"Ref[in 'a, out 'a ∨ int]",
+				"Ref[in ⊥, out 'a] -> 'a",
+				"Ref[in 'a, out ⊤] -> 'a -> unit",
+				"int",
+				"unit",
+				"'a -> Ref['a]",
+				"⊤ -> unit",
+				// value restriction: rid 没有泛化, 赋值之后只能存放 int -> int
+				"Ref[in 'a, out 'a ∨ (int -> int)]",
+				"unit",
+			},
+		},
+		{
+			"mutual-recursion",
+			`
+	let rec even = fun n -> case n of { Z -> true, S m -> odd m }
+	and odd = fun n -> case n of { Z -> false, S m -> even m }
+	let res = even (S (S Z))
+	let rec f = fun x -> g x and g = fun x -> { a: f x, b: x }
+	let t = let rec p = fun x -> q x and q = fun y -> p y in p
+`,
+			[]string{
+				"[S [S 'a | Z] | Z] as 'a -> bool",
+				"[S [S 'a | Z] | Z] as 'a -> bool",
+				"bool",
+				"'a -> {a: 'b, b: 'a} as 'b",
+				"'a -> {a: 'b, b: 'a} as 'b",
+				"⊤ -> ⊥",
+			},
+		},
+		{
+			"out-of-order",
+			`
+	let main = fun n -> isEven (inc n)
+	let inc = fun n -> succ n
+	let rec isEven = fun n -> if true then true else isOdd (inc n)
+	let rec isOdd = fun n -> if true then false else isEven (inc n)
+	let x = y
+	let y = 1
+	let y = x
+`,
+			[]string{
+				"int -> bool",
+				"int -> int",
+				"int -> bool",
+				"int -> bool",
+				"int",
+				"int",
+				"int",
+			},
+		},
+		{
+			// 之后的定义不遮蔽 builtins, 只解析 builtins 中没有的名字
+			"shadowing",
+			`
+	let a = succ 1
+	let succ = fun x -> { v: x }
+	let b = succ 1
+	let c = pair 1
+	let pair = fun x -> (x, x)
+	let d = add 1 2
+	let add = fun x -> x
+	let e = add true
+`,
+			[]string{
+				"int",
+				"'a -> {v: 'a}",
+				"{v: int}",
+				"(int, int)",
+				"'a -> ('a, 'a)",
+				"int",
+				"'a -> 'a",
+				"bool",
+			},
+		},
+		{
+			"record-extension",
+			`
+	let base = { x: 1, y: true }
+	let moved = { base with x: "s", z: 1 }
+	let dropped = base \ y
+	let setX = fun r -> { r with x: 1 }
+	let dropX = fun r -> r \ x
+	let retype = fun r -> { r \ x with x: true }
+	let applied = setX { y: 2 }
+	let either = fun c -> fun r -> if c then { r with x: 1 } else { r with y: 2 }
+	let both = fun c -> fun r -> fun s -> if c then { r with x: 1 } else { s with x: true }
+`,
+			[]string{
+				"{x: int, y: bool}",
+				"{x: string, y: bool, z: int}",
+				"{x: int}",
+				"'a ∧ {} -> {'a with x: int}",
+				"'a ∧ {} -> 'a \\ x",
+				"'a ∧ {} -> {'a with x: bool}",
+				"{x: int, y: int}",
+				"bool -> 'a ∧ {} -> {'a with x: int} ∨ {'a with y: int}",
+				"bool -> 'a ∧ {} -> 'a ∧ {} -> {'a with x: bool ∨ int}",
+			},
+		},
 		{
 			"misc",
 			`
@@ -181,3 +318,109 @@ func TestPgrm(t *testing.T) {
 		})
 	}
 }
+
+func TestPgrmError(t *testing.T) {
+	var typer = NewTyper()
+
+	for _, tt := range []struct {
+		pgrm     string
+		expected string
+	}{
+		{
+			"let f = fun x -> g x\nlet g = fun x -> f x",
+			"cyclic definition through non-rec f: f -> g -> f",
+		},
+		{
+			"let rec f = fun x -> g x\nlet g = fun x -> h x\nlet rec h = fun x -> f x",
+			"cyclic definition through non-rec g: g -> h -> f -> g",
+		},
+		{
+			"let rec f = fun x -> x and f = 1",
+			"duplicate definition in let rec group: f",
+		},
+		{
+			// value restriction: 闭包捕获的引用不出现在 c 的类型中, c 仍然不能泛化
+			"let c = let r = ref 0 in fun x -> let old = !r in let u = r := x in old\n" +
+				"let main = let a = c (fun y -> y) in add (c 1) 1",
+			"no overload of (int -> int -> int) ∧ (float -> float -> float) matches 'a ∨ ('b -> 'b) ∨ int",
+		},
+	} {
+		t.Run(tt.pgrm, func(t *testing.T) {
+			_, err := typer.inferTypes(parsePgrm(tt.pgrm), typer.Builtins())
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("expect %s actual %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+// typeCase 程序最后一个定义展示出的类型, 或推导报出的错误
+type typeCase struct {
+	pgrm     string
+	expected string
+}
+
+// env 为每个用例提供 Typer 与初始环境
+type env func() (*Typer, *Ctx)
+
+// freshEnv 每个用例新建 Typer, ctx 为 nil 时使用内置环境
+func freshEnv(ctx func(*Typer) *Ctx, opts ...Option) env {
+	return func() (*Typer, *Ctx) {
+		typer := NewTyper(opts...)
+		if ctx == nil {
+			return typer, typer.Builtins()
+		}
+		return typer, ctx(typer)
+	}
+}
+
+// sharedEnv 用例共用一个 Typer, 各自使用 ctx 的副本
+func sharedEnv(typer *Typer, ctx *Ctx) env {
+	return func() (*Typer, *Ctx) { return typer, ctx.clone() }
+}
+
+// withDecls 在每个用例前加上共用的声明
+func withDecls(decls string, cases []typeCase) []typeCase {
+	res := make([]typeCase, len(cases))
+	for i, tt := range cases {
+		res[i] = typeCase{decls + tt.pgrm, tt.expected}
+	}
+	return res
+}
+
+// showLast 推导程序, 按 Typer 的配置展示最后一个定义的类型
+func showLast(typer *Typer, ctx *Ctx, pgrm string) (string, error) {
+	tyv, err := typer.inferTypes(parsePgrm(pgrm), ctx)
+	if err != nil {
+		return "", err
+	}
+	st := tyv[len(tyv)-1].instantiate(typer, 0)
+	return typer.stages(st).Coalesced.Show(), nil
+}
+
+func testTypes(t *testing.T, env env, cases []typeCase) {
+	for _, tt := range cases {
+		t.Run(tt.pgrm, func(t *testing.T) {
+			typer, ctx := env()
+			res, err := showLast(typer, ctx, tt.pgrm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tt.expected {
+				t.Errorf("expect %s actual %s", tt.expected, res)
+			}
+		})
+	}
+}
+
+func testErrors(t *testing.T, env env, cases []typeCase) {
+	for _, tt := range cases {
+		t.Run(tt.pgrm, func(t *testing.T) {
+			typer, ctx := env()
+			_, err := typer.inferTypes(parsePgrm(tt.pgrm), ctx)
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("expect %s actual %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package typer
+
+import (
+	"testing"
+
+	"github.com/goghcrow/simple-sub/terms"
+)
+
+func TestPipelineTerm(t *testing.T) {
+	typer := NewTyper()
+	p := NewPipeline(typer, typer.Builtins())
+
+	// fun x -> x 42
+	res, err := p.Term(terms.Lam("x", terms.App(terms.Var("x"), terms.Int(42))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `inferred: (α1 -> α2)
+where: α1 <: (int -> α2)
+compacted: ‹‹α1, ‹int› -> ‹α2›› -> ‹α2››
+simplified: ‹‹‹int› -> ‹α2›› -> ‹α2››
+coalesced: (int -> 'a) -> 'a
+`
+	if res.String() != expect {
+		t.Errorf("expect %s actual %s", expect, res)
+	}
+
+	_, err = p.Term(terms.App(terms.Var("succ"), terms.Var("true")))
+	if err == nil || err.Error() != "cannot constrain bool <: int" {
+		t.Errorf("expect type error actual %v", err)
+	}
+}
+
+func TestPipelineProgram(t *testing.T) {
+	typer := NewTyper()
+	p := NewPipeline(typer, typer.Builtins())
+
+	pgrm := parsePgrm(`
+	let id = fun x -> x
+	let rec recursive_monster = fun x -> { thing: x, self: recursive_monster x }
+`)
+	res, err := p.Program(pgrm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expect := range []struct {
+		compacted  string
+		simplified string
+		coalesced  string
+	}{
+		{"‹‹α5› -> ‹α5››", "‹‹α5› -> ‹α5››", "'a -> 'a"},
+		{
+			"‹α6, ‹α7› -> ‹{self: ‹α9›, thing: ‹α7›}›› where α9 = ‹α8, {self: ‹α9›, thing: ‹α7›}›",
+			"‹‹α7› -> ‹{self: ‹α9›, thing: ‹α7›}›› where α9 = ‹{self: ‹α9›, thing: ‹α7›}›",
+			"'a -> {self: 'b, thing: 'a} as 'b",
+		},
+	} {
+		if actual := res[i].Compacted.String(); actual != expect.compacted {
+			t.Errorf("compacted: expect %s actual %s", expect.compacted, actual)
+		}
+		if actual := res[i].Simplified.String(); actual != expect.simplified {
+			t.Errorf("simplified: expect %s actual %s", expect.simplified, actual)
+		}
+		if actual := res[i].Coalesced.Show(); actual != expect.coalesced {
+			t.Errorf("coalesced: expect %s actual %s", expect.coalesced, actual)
+		}
+	}
+
+	// Program 不修改 Pipeline 的 ctx
+	if _, err := p.Term(terms.Var("id")); err == nil {
+		t.Errorf("expect identifier not found")
+	}
+}
@@ -0,0 +1,170 @@
+package typer
+
+import "github.com/goghcrow/simple-sub/terms"
+
+// primLattice 基本类型之间的子类型关系, 保存传递闭包, 不包含自反
+// sub -> 所有严格父类型, e.g. nat <: int <: float 保存 nat -> {int, float}, int -> {float}
+type primLattice map[*Primitive]map[*Primitive]bool
+
+func (l primLattice) isSub(sub, sup *Primitive) bool { return l[sub][sup] }
+
+// add 加入 sub <: sup 并维护传递闭包: 所有 x <: sub 与 sup <: y 都有 x <: y
+func (l primLattice) add(sub, sup *Primitive) {
+	lows := []*Primitive{sub}
+	for x, sups := range l {
+		if sups[sub] {
+			lows = append(lows, x)
+		}
+	}
+	highs := []*Primitive{sup}
+	for y := range l[sup] {
+		highs = append(highs, y)
+	}
+	for _, x := range lows {
+		if l[x] == nil {
+			l[x] = map[*Primitive]bool{}
+		}
+		for _, y := range highs {
+			l[x][y] = true
+		}
+	}
+}
+
+// typeDecls 类型声明
+//
+// Typer 的 base 由 DeclarePrim 配置, 对所有程序可见。
+// 每个程序在 base 的副本上声明源码中的类型, 推导失败时丢弃, 不影响之后的程序,
+// 推导成功时保留到下一个程序开始, 用于输出推导结果
+type typeDecls struct {
+	// 基本类型的子类型关系
+	prims primLattice
+	// 已声明的基本类型与名义类型, 名字到基本类型, 类型表达式中只能使用已声明的名字
+	// 基本类型不在全局驻留, 同一个名字在不同的 Typer 中是不同的基本类型
+	names map[string]*Primitive
+	// 名义类型 (类与特质) 声明的字段, 参见 typer_classes.go
+	classes map[*Primitive]*classInfo
+	// 参数化的类型构造器, 参见 typer_ctors.go
+	ctors map[string]*ctorInfo
+}
+
+func (d *typeDecls) clone() *typeDecls {
+	c := &typeDecls{
+		prims:   make(primLattice, len(d.prims)),
+		names:   make(map[string]*Primitive, len(d.names)),
+		classes: make(map[*Primitive]*classInfo, len(d.classes)),
+		ctors:   make(map[string]*ctorInfo, len(d.ctors)),
+	}
+	for sub, sups := range d.prims {
+		c.prims[sub] = make(map[*Primitive]bool, len(sups))
+		for sup := range sups {
+			c.prims[sub][sup] = true
+		}
+	}
+	for name, p := range d.names {
+		c.names[name] = p
+	}
+	for p, info := range d.classes {
+		c.classes[p] = info
+	}
+	for name, info := range d.ctors {
+		c.ctors[name] = info
+	}
+	return c
+}
+
+// beginProgram 在 base 的副本上声明程序中的类型, 并清空上一个程序的运行时转换
+func (t *Typer) beginProgram() {
+	t.typeDecls = t.base.clone()
+	t.casts = nil
+}
+
+// DeclarePrim 声明基本类型 sub <: sup, e.g. t.DeclarePrim("nat", "int")
+// 子类型关系是传递的, 形成环时报错, 对之后推导的所有程序可见
+func (t *Typer) DeclarePrim(sub, sup string) (err error) {
+	t.typeDecls = t.base.clone()
+	defer func() {
+		if r := recover(); r != nil {
+			t.typeDecls = t.base
+			err = TypeErrorOf(r)
+			return
+		}
+		t.base = t.typeDecls
+	}()
+	t.declarePrim(t.prim(sub), t.prim(sup))
+	return
+}
+
+// Prim Typer 中名为 name 的基本类型, 没有声明过时在 base 中声明, 对之后推导的所有程序可见
+// 宿主在内置环境中用它引用基本类型与名义类型, e.g. ctx.Add("describe", Fun(typer.Prim("Showable"), String))
+func (t *Typer) Prim(name string) *Primitive {
+	p := t.base.names[name]
+	if p == nil {
+		p = &Primitive{Name: name}
+		t.base.names[name] = p
+	}
+	if t.names[name] == nil {
+		t.names[name] = p
+	}
+	return p
+}
+
+// prim 当前程序中名为 name 的基本类型, 没有声明过时声明
+func (t *Typer) prim(name string) *Primitive {
+	p := t.names[name]
+	if p == nil {
+		p = &Primitive{Name: name}
+		t.names[name] = p
+	}
+	return p
+}
+
+func (t *Typer) declarePrim(sub, sup *Primitive) {
+	if sub == sup || t.prims.isSub(sup, sub) {
+		panic(NewTypeError("cyclic primitive subtyping: %s <: %s <: %s", sub, sup, sub))
+	}
+	t.prims.add(sub, sup)
+}
+
+// declareTypes 处理源码中的类型声明, e.g. type nat <: int, class Point { x: int }
+func (t *Typer) declareTypes(decls []terms.TypeDecl) {
+	var ctors []*terms.CtorDecl
+	var classes []*terms.ClassDecl
+	for _, decl := range decls {
+		switch d := decl.(type) {
+		case *terms.PrimDecl:
+			p := t.prim(d.Name)
+			if d.Super != "" {
+				t.declarePrim(p, t.prim(d.Super))
+			}
+		case *terms.CtorDecl:
+			ctors = append(ctors, d)
+		case *terms.ClassDecl:
+			classes = append(classes, d)
+		default:
+			panic("unreached")
+		}
+	}
+	// 类的字段可以使用构造器
+	t.declareCtors(ctors)
+	t.declareClasses(classes)
+}
+
+// hasCommonSub 是否有声明的基本类型同时是 p 与 q 的子类型, e.g. type a <: x, type a <: y
+func (t *Typer) hasCommonSub(p, q *Primitive) bool {
+	for _, sups := range t.prims {
+		if sups[p] && sups[q] {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubPrim p <: q, 单例类型是基类型的子类型
+func (t *Typer) isSubPrim(p, q *Primitive) bool {
+	for x := p; x != nil; x = x.Base {
+		if samePrim(x, q) || t.prims.isSub(x, q) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestPrimLattice(t *testing.T) {
+	typer := NewTyper()
+	if err := typer.DeclarePrim("nat", "int"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := typer.Builtins()
+	ctx.Add("natSucc", Fun(typer.Prim("nat"), typer.Prim("nat")))
+	ctx.Add("sqrt", Fun(Float, Float))
+	ctx.Add("genId", Fun(Int, typer.Prim("uuid")))
+	ctx.Add("len", Fun(String, Int))
+
+	testTypes(t, sharedEnv(typer, ctx), []typeCase{
+		// nat ∧ int = nat, nat ∨ int = int
+		{"let f = fun x -> if true then natSucc x else succ x", "nat -> int"},
+		// nat <: int <: float
+		{"let f = fun x -> sqrt (natSucc x)", "nat -> float"},
+		{"let f = fun x -> if true then natSucc x else sqrt x", "nat -> float"},
+		// 源码声明
+		{"type uuid <: string\nlet n = len (genId 1)", "int"},
+	})
+
+	testErrors(t, sharedEnv(typer, ctx), []typeCase{
+		{"let x = natSucc 1", "cannot constrain int <: nat"},
+		{"let x = natSucc 1.5", "cannot constrain float <: nat"},
+		{"type a <: b\ntype b <: c\ntype c <: a", "cyclic primitive subtyping: c <: a <: c"},
+	})
+
+	// 源码声明的子类型关系只属于所在的程序, 推导失败的程序不留下部分声明
+	if _, err := typer.inferTypes(parsePgrm("let n = len (genId 1)"), ctx.clone()); err == nil || err.Error() != "cannot constrain uuid <: string" {
+		t.Errorf("expect cannot constrain uuid <: string actual %v", err)
+	}
+	if typer.names["a"] != nil || typer.names["b"] != nil {
+		t.Errorf("expect no primitives declared by a rejected program")
+	}
+
+	if err := typer.DeclarePrim("float", "nat"); err == nil || err.Error() != "cyclic primitive subtyping: float <: nat <: float" {
+		t.Errorf("expect cycle error actual %v", err)
+	}
+
+	// 基本类型与子类型关系属于 Typer
+	other := NewTyper()
+	if other.Prim("nat") == typer.Prim("nat") || other.isSubPrim(other.Prim("nat"), Int) {
+		t.Errorf("expect nat </: int in a fresh typer")
+	}
+}
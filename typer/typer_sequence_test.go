@@ -0,0 +1,31 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestSequence(t *testing.T) {
+	testTypes(t, freshEnv(nil), []typeCase{
+		{"let x = ()", "unit"},
+		{"let f = fun x -> (); x", "'a -> 'a"},
+		{"let f = fun r -> r := 1; !r", "Ref[in int, out 'a] -> 'a"},
+		{"let r = ref 0;;\nr := !r + 1;;\n!r;;", "int"},
+		{"let f = fun g -> g (); g ()", "(unit -> 'a ∧ unit) -> 'a"},
+		{"let x = let y = 1 in (); y", "int"},
+	})
+
+	// 顶层表达式与定义一样推导类型, 但不绑定名字
+	typer := NewTyper()
+	tys, err := typer.InferTypes(parsePgrm("let x = 1;;\nx + 1;;\n(x, ());;"), typer.Builtins())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tys) != 3 || tys[1].Show() != "int" || tys[2].Show() != "(int, unit)" {
+		t.Errorf("expect [int int (int, unit)] actual %v", tys)
+	}
+
+	testErrors(t, freshEnv(nil), []typeCase{
+		{"let x = 1; 2", "cannot constrain int <: unit"},
+		{"let f = fun x -> x + 1; x", "cannot constrain int <: unit"},
+	})
+}
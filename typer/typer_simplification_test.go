@@ -0,0 +1,74 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestSimplification(t *testing.T) {
+	src := `
+	let twice = fun f -> fun x -> f (f x)
+	let rec recursive_monster = fun x -> { thing: x, self: recursive_monster x }
+	let rec produce = fun arg -> { head: arg, tail: produce (succ arg) }
+`
+	for _, tt := range []struct {
+		name   string
+		s      Simplification
+		expect []string
+	}{
+		{
+			"none",
+			SimplifyNone,
+			[]string{
+				"'a ∧ ('b -> 'c) ∧ ('d -> 'b) -> 'd -> 'c",
+				"'a ∨ ('b -> {thing: 'b, self: ('d ∨ {thing: 'b, self: 'c}) as 'c})",
+				"'a ∨ ('b ∧ int -> {head: 'b ∨ int, tail: ('d ∨ {head: 'b ∨ int, tail: 'c}) as 'c})",
+			},
+		},
+		{
+			"no co-occurrence",
+			Simplification{Canonicalize: true, HashConsing: true},
+			[]string{
+				"'a ∧ ('b ∨ 'c -> 'c ∧ 'd) -> 'b -> 'd",
+				"'a ∨ ('b -> {self: ('d ∨ {self: 'c, thing: 'b}) as 'c, thing: 'b})",
+				"'a ∨ ('b ∧ int -> {head: 'b ∨ int, tail: ('d ∨ {head: 'b ∨ int, tail: 'c}) as 'c})",
+			},
+		},
+		{
+			"no hash-consing",
+			Simplification{Canonicalize: true, CoOccurrence: true},
+			[]string{
+				"('a ∨ 'b -> 'b) -> 'a -> 'b",
+				"'a -> {self: {self: 'b, thing: 'a} as 'b, thing: 'a}",
+				"int -> {head: int, tail: {head: int, tail: 'a} as 'a}",
+			},
+		},
+		{
+			"full",
+			SimplifyFull,
+			[]string{
+				"('a ∨ 'b -> 'b) -> 'a -> 'b",
+				"'a -> {self: 'b, thing: 'a} as 'b",
+				"int -> {head: int, tail: 'a} as 'a",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			typer := NewTyper(WithSimplification(tt.s))
+			res, err := NewPipeline(typer, typer.Builtins()).Program(parsePgrm(src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, r := range res {
+				if actual := r.Coalesced.Show(); actual != tt.expect[i] {
+					t.Errorf("expect %s actual %s", tt.expect[i], actual)
+				}
+				if (r.Compacted != nil) != tt.s.Canonicalize {
+					t.Errorf("compacted stage: expect present=%t", tt.s.Canonicalize)
+				}
+				if (r.Simplified != nil) != (tt.s.Canonicalize && tt.s.CoOccurrence) {
+					t.Errorf("simplified stage: unexpected %v", r.Simplified)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestSingletons(t *testing.T) {
+	src := `
+	let get = { method: "GET" }
+	let config = fun b -> { method: if b then "GET" else "POST", port: 8080 }
+	let widened = fun b -> if b then "GET" else get.method
+	let port = succ 8080
+	let ratio = 3.0
+	let pick = fun x -> if true then x else "a"
+`
+	for _, tt := range []struct {
+		name   string
+		opts   []Option
+		expect []string
+	}{
+		{
+			"off",
+			nil,
+			[]string{
+				"{method: string}",
+				"bool -> {method: string, port: int}",
+				"bool -> string",
+				"int",
+				"float",
+				"'a -> 'a ∨ string",
+			},
+		},
+		{
+			"on",
+			[]Option{WithSingletons(true)},
+			[]string{
+				`{method: "GET"}`,
+				`bool -> {method: "GET" ∨ "POST", port: 8080}`,
+				`bool -> "GET"`,
+				"int",
+				"3.0",
+				`'a -> 'a ∨ "a"`,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			typer := NewTyper(tt.opts...)
+			pgrm := parsePgrm(src)
+			tyv, err := typer.inferTypes(pgrm, typer.Builtins())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, poly := range tyv {
+				st := poly.instantiate(typer, 0)
+				res := typer.coalesceCompactType(typer.simplifyType(typer.canonicalizeType(st))).Show()
+				if res != tt.expect[i] {
+					t.Errorf("%s: expect %s actual %s", pgrm.Defs[i].Name, tt.expect[i], res)
+				}
+			}
+		})
+	}
+
+	// "GET" ∨ string 拓宽为 string, 负极 "GET" ∧ string 保留单例类型
+	typer := NewTyper(WithSingletons(true))
+	ctx := typer.Builtins()
+	ctx.Add("upper", Fun(String, String))
+	ctx.Add("isGet", Fun(Lit(`"GET"`, String), Bool))
+	testTypes(t, sharedEnv(typer, ctx), []typeCase{
+		{`let f = fun b -> fun s -> if b then "GET" else upper s`, "bool -> string -> string"},
+		{`let f = fun s -> if isGet s then upper s else "POST"`, `"GET" -> string`},
+	})
+
+	_, err := typer.inferTypes(parsePgrm(`let x = succ "1"`), typer.Builtins())
+	if err == nil || err.Error() != `cannot constrain "1" <: int` {
+		t.Errorf("expect type error actual %v", err)
+	}
+}
+
+// 类型标注中的字面量类型与并集, e.g. 校验配置
+func TestLiteralTypes(t *testing.T) {
+	testTypes(t, freshEnv(nil, WithSingletons(true)), []typeCase{
+		{`let get = ({method: "GET"} : {method: "GET" ∨ "POST"})`, `{method: "GET" ∨ "POST"}`},
+		{`let validate = fun c -> (c : {method: "GET" ∨ "POST", port: int})`, `{method: "GET" ∨ "POST", port: int} -> {method: "GET" ∨ "POST", port: int}`},
+		{`let f = fun c -> (c : {method: "GET" ∨ "POST"}).method`, `{method: "GET" ∨ "POST"} -> "GET" ∨ "POST"`},
+		{`let f = fun c -> concat (c : {method: "GET" ∨ "POST"}).method "!"`, `{method: "GET" ∨ "POST"} -> string`},
+		{`let f = fun x -> if (x : "a" ∨ "b") == "a" then 1 else 2`, `"a" ∨ "b" -> 1 ∨ 2`},
+		{`let x = (8080 : 8080 ∨ 8443)`, "8080 ∨ 8443"},
+		{`let x = fun b -> (if b then 1 else "a" : int ∨ string)`, "bool -> int ∨ string"},
+		{"type Method = \"GET\" ∨ \"POST\"\nlet f = fun m -> (m : Method)", "Method -> Method"},
+	})
+	testErrors(t, freshEnv(nil, WithSingletons(true)), []typeCase{
+		{`let bad = ({method: "PUT"} : {method: "GET" ∨ "POST"})`, `cannot constrain "PUT" <: "GET" ∨ "POST"`},
+		{`let x = fun b -> (if b then 1 else true : int ∨ string)`, "cannot constrain bool <: int ∨ string"},
+		{"type Opt[A] = A ∨ unit\nlet x = 1", "union type must be closed: A ∨ unit"},
+	})
+	// 关闭时字面量的类型是基本类型, 不是单例类型的子类型
+	testErrors(t, freshEnv(nil), []typeCase{
+		{`let get = ({method: "GET"} : {method: "GET" ∨ "POST"})`, `cannot constrain string <: "GET" ∨ "POST"`},
+	})
+}
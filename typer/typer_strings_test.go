@@ -0,0 +1,29 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestStrings(t *testing.T) {
+	testTypes(t, freshEnv(nil), []typeCase{
+		{"let x = \"a ${1} b\"", "string"},
+		{"let x = \"${1.5}${true}\"", "string"},
+		{"let x = \"\\${1}\"", "string"},
+		{"let f = fun x -> \"v=${x}\"", "eq -> string"},
+		{"let show = fun x -> \"v=${x}\"\nlet y = (show 1, show true, show {a: 1.5})", "(string, string, string)"},
+		{"let x = \"a ${concat \"b\" \"${1}\"}\"", "string"},
+		{"let x = \"${{a: 1}}\"", "string"},
+		{"let f = fun x -> \"v=${x + 1}\"", "int -> string"},
+		{"let f = fun r -> \"${r.name}: ${r.age}\"", "{age: eq, name: eq} -> string"},
+		{"let x = length \"abc\"", "int"},
+		{"let x = substring \"abc\" 0 1", "string"},
+		{"let f = fun s -> substring s 0 (length s)", "string -> string"},
+		{"let x = concat \"a\" (toString 1)", "string"},
+	})
+
+	testErrors(t, freshEnv(nil), []typeCase{
+		{"let x = concat \"a\" 1", "cannot constrain int <: string"},
+		{"let x = length 1", "cannot constrain int <: string"},
+		{"let x = \"${fun x -> x}\"", "cannot constrain 'a -> 'a <: eq"},
+	})
+}
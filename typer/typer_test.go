@@ -170,6 +170,60 @@ func TestRecords(t *testing.T) {
 			terms.Lam("x", terms.Sel(terms.Rcd([]terms.Field{{"a", terms.Var("x")}}), "b")),
 			expected{"", "", "", "", "", NewTypeError("missing field: b in {a: 'a}")},
 		},
+		{
+			"fun r -> { r with x: 1 }",
+			terms.Lam("r", terms.Ext(terms.Var("r"), []terms.Field{{"x", terms.Int(1)}})),
+			expected{"(α1 -> {α1 with x: int})", "α1 <: {}", "‹‹α1, {}› -> ‹{‹α1› with x: ‹int›}››", "‹‹α1, {}› -> ‹{‹α1› with x: ‹int›}››", "'a ∧ {} -> {'a with x: int}", nil},
+		},
+		{
+			"fun r -> (r \\ x).y",
+			terms.Lam("r", terms.Sel(terms.Rst(terms.Var("r"), "x"), "y")),
+			expected{"(α1 -> α2)", "α1 <: {y: α2} & {}", "‹‹α1, {y: ‹α2›}› -> ‹α2››", "‹‹{y: ‹α2›}› -> ‹α2››", "{y: 'a} -> 'a", nil},
+		},
+		{
+			"({ x: 1, y: true } \\ x).x",
+			terms.Sel(terms.Rst(terms.Rcd([]terms.Field{{"x", terms.Int(1)}, {"y", terms.Var("true")}}), "x"), "x"),
+			expected{"", "", "", "", "", NewTypeError("missing field: x in {y: bool}")},
+		},
+		{
+			"fun r -> (r \\ x).x",
+			terms.Lam("r", terms.Sel(terms.Rst(terms.Var("r"), "x"), "x")),
+			expected{"", "", "", "", "", NewTypeError("missing field: x in 'a \\ x")},
+		},
+		{
+			"{ 1 with x: 1 }",
+			terms.Ext(terms.Int(1), []terms.Field{{"x", terms.Int(1)}}),
+			expected{"", "", "", "", "", NewTypeError("cannot constrain int <: {}")},
+		},
+	} {
+		t.Run(tt.string, func(t *testing.T) { doTest(t, tt) })
+	}
+}
+
+func TestVariants(t *testing.T) {
+	for _, tt := range []testCase{
+		{
+			"case Other 1 of { Some x -> x, None -> 0 }",
+			terms.Cas(terms.Tg("Other", terms.Int(1)), []terms.Arm{{"Some", "x", terms.Var("x")}, {"None", "", terms.Int(0)}}),
+			expected{"", "", "", "", "", NewTypeError("missing case: Other in [Some 'a | None 'b]")},
+		},
+		{
+			"case None of { None -> 1, None -> 0 }",
+			terms.Cas(terms.Tg("None", nil), []terms.Arm{{"None", "", terms.Int(1)}, {"None", "", terms.Int(0)}}),
+			expected{"", "", "", "", "", NewTypeError("duplicate case: None")},
+		},
+	} {
+		t.Run(tt.string, func(t *testing.T) { doTest(t, tt) })
+	}
+}
+
+func TestRefs(t *testing.T) {
+	for _, tt := range []testCase{
+		{
+			"let rid = ref (fun x -> x) in let a = rid := (fun x -> succ x) in (!rid) true",
+			terms.Let("rid", terms.NRef(terms.Lam("x", terms.Var("x"))), terms.Let("a", terms.Asgn(terms.Var("rid"), terms.Lam("x", terms.App(terms.Var("succ"), terms.Var("x")))), terms.App(terms.Drf(terms.Var("rid")), terms.Var("true")), false), false),
+			expected{"", "", "", "", "", NewTypeError("cannot constrain bool <: int")},
+		},
 	} {
 		t.Run(tt.string, func(t *testing.T) { doTest(t, tt) })
 	}
@@ -277,42 +331,45 @@ func TestLetPoly(t *testing.T) {
 		{
 			"fun k -> let test = k (fun x -> let tmp = add x 1 in x) in test",
 			terms.Lam("k", terms.Let("test", terms.App(terms.Var("k"), terms.Lam("x", terms.Let("tmp", terms.App(terms.App(terms.Var("add"), terms.Var("x")), terms.Int(1)), terms.Var("x"), false))), terms.Var("test"), false)),
-			expected{"(α1 -> α9)", "α1 <: ((α6 -> α7) -> α8), α6 <: int, α7 :> α6, α9 :> α8", "‹‹α1, ‹‹α6, int› -> ‹α7, α6›› -> ‹α8›› -> ‹α9, α8››", "‹‹‹‹α6, int› -> ‹α6›› -> ‹α8›› -> ‹α8››", "(('a ∧ int -> 'a) -> 'b) -> 'b", nil},
+			expected{"(α1 -> α5)", "α1 <: ((α2 -> α2) -> α5), α2 <: int", "‹‹α1, ‹‹α2, int› -> ‹α2›› -> ‹α5›› -> ‹α5››", "‹‹‹‹α2, int› -> ‹α2›› -> ‹α5›› -> ‹α5››", "(('a ∧ int -> 'a) -> 'b) -> 'b", nil},
 		},
 		{
 			"fun k -> let test = k (fun x -> let tmp = add x 1 in if true then x else 2) in test",
 			terms.Lam("k", terms.Let("test", terms.App(terms.Var("k"), terms.Lam("x", terms.Let("tmp", terms.App(terms.App(terms.Var("add"), terms.Var("x")), terms.Int(1)), terms.App(terms.App(terms.App(terms.Var("if"), terms.Var("true")), terms.Var("x")), terms.Int(2)), false))), terms.Var("test"), false)),
-			expected{"(α1 -> α15)", "α1 <: ((α10 -> α13) -> α14), α10 <: α11 & int, α11 <: α12, α13 :> α12 | int, α15 :> α14", "‹‹α1, ‹‹α10, α11, α12, int› -> ‹α13, α12, int›› -> ‹α14›› -> ‹α15, α14››", "‹‹‹‹int› -> ‹int›› -> ‹α14›› -> ‹α14››", "((int -> int) -> 'a) -> 'a", nil},
+			expected{"(α1 -> α9)", "α1 <: ((α2 -> α8) -> α9), α2 <: α5 & int, α5 :> int <: α8, α8 :> int", "‹‹α1, ‹‹α2, α5, α8, int› -> ‹α8, int›› -> ‹α9›› -> ‹α9››", "‹‹‹‹int› -> ‹int›› -> ‹α9›› -> ‹α9››", "((int -> int) -> 'a) -> 'a", nil},
 		},
 		{
 			"fun k -> let test = (fun id -> {tmp = k id; res = id}.res) (fun x -> x) in {u=test 0; v=test true}",
 			terms.Lam("k", terms.Let("test", terms.App(terms.Lam("id", terms.Sel(terms.Rcd([]terms.Field{{"tmp", terms.App(terms.Var("k"), terms.Var("id"))}, {"res", terms.Var("id")}}), "res")), terms.Lam("x", terms.Var("x"))), terms.Rcd([]terms.Field{{"u", terms.App(terms.Var("test"), terms.Int(0))}, {"v", terms.App(terms.Var("test"), terms.Var("true"))}}), false)),
-			expected{"(α1 -> {u: α13, v: α16})", "α1 <: (α4 -> α5), α4 :> (α9 -> α10), α9 <: α16 & α13, α10 :> bool | int | α9, α13 :> int, α16 :> bool", "‹‹α1, ‹α4, ‹α9, α16, α13› -> ‹α10, α9, bool, int›› -> ‹α5›› -> ‹{u: ‹α13, int›, v: ‹α16, bool›}››", "‹‹‹‹α16› -> ‹α16, bool, int›› -> ‹›› -> ‹{u: ‹α16, int›, v: ‹α16, bool›}››", "(('a -> 'a ∨ bool ∨ int) -> ⊤) -> {u: 'a ∨ int, v: 'a ∨ bool}", nil},
+			// value restriction: test 不是值, 没有泛化, 两次使用共享同一个类型
+			expected{"(α1 -> {u: α7, v: α8})", "α1 <: (α2 -> α3), α2 :> (α5 -> α5) <: α4, α4 :> (α5 -> α5) <: α6, α5 :> bool | int <: α8 & α7, α6 :> (α5 -> α5) <: (bool -> α8) & (int -> α7), α7 :> bool | int, α8 :> int | bool", "‹‹α1, ‹α2, ‹α5, α7, α8› -> ‹α5, bool, int›› -> ‹α3›› -> ‹{u: ‹α7, bool, int›, v: ‹α8, bool, int›}››", "‹‹‹‹α8› -> ‹α8, bool, int›› -> ‹›› -> ‹{u: ‹α8, bool, int›, v: ‹α8, bool, int›}››", "(('a -> 'a ∨ bool ∨ int) -> ⊤) -> {u: 'a ∨ bool ∨ int, v: 'a ∨ bool ∨ int}", nil},
 		},
 		{
 			"fun k -> let test = {tmp = k (fun x -> x); res = (fun x -> x)}.res in {u=test 0; v=test true}",
 			terms.Lam("k", terms.Let("test", terms.Sel(terms.Rcd([]terms.Field{{"tmp", terms.App(terms.Var("k"), terms.Lam("x", terms.Var("x")))}, {"res", terms.Lam("x", terms.Var("x"))}}), "res"), terms.Rcd([]terms.Field{{"u", terms.App(terms.Var("test"), terms.Int(0))}, {"v", terms.App(terms.Var("test"), terms.Var("true"))}}), false)),
-			expected{"(α1 -> {u: α11, v: α14})", "α1 <: ((α4 -> α5) -> α6), α5 :> α4, α11 :> int, α14 :> bool", "‹‹α1, ‹‹α4› -> ‹α5, α4›› -> ‹α6›› -> ‹{u: ‹α11, int›, v: ‹α14, bool›}››", "‹‹‹‹α4› -> ‹α4›› -> ‹›› -> ‹{u: ‹int›, v: ‹bool›}››", "(('a -> 'a) -> ⊤) -> {u: int, v: bool}", nil},
+			// value restriction: test 不是值, 没有泛化, 两次使用共享同一个类型
+			expected{"(α1 -> {u: α6, v: α7})", "α1 <: ((α2 -> α2) -> α3), α6 :> bool | int, α7 :> int | bool", "‹‹α1, ‹‹α2› -> ‹α2›› -> ‹α3›› -> ‹{u: ‹α6, bool, int›, v: ‹α7, bool, int›}››", "‹‹‹‹α2› -> ‹α2›› -> ‹›› -> ‹{u: ‹bool, int›, v: ‹bool, int›}››", "(('a -> 'a) -> ⊤) -> {u: bool ∨ int, v: bool ∨ int}", nil},
 		},
 		{
 			"fun k -> let test = (fun thefun -> {l=k thefun; r=thefun 1}) (fun x -> let tmp = add x 1 in x) in test",
 			terms.Lam("k", terms.Let("test", terms.App(terms.Lam("thefun", terms.Rcd([]terms.Field{{"l", terms.App(terms.Var("k"), terms.Var("thefun"))}, {"r", terms.App(terms.Var("thefun"), terms.Int(1))}})), terms.Lam("x", terms.Let("tmp", terms.App(terms.App(terms.Var("add"), terms.Var("x")), terms.Int(1)), terms.Var("x"), false))), terms.Var("test"), false)),
-			expected{"(α1 -> α14)", "α1 <: (α4 -> α5), α4 :> (α11 -> α13), α11 <: α12 & int, α13 :> α11 | int, α14 :> {l: α15, r: α16}, α15 :> α5, α16 :> α12 | int", "‹‹α1, ‹α4, ‹α11, α12, int› -> ‹α13, α11, int›› -> ‹α5›› -> ‹α14, {l: ‹α15, α5›, r: ‹α16, α12, int›}››", "‹‹‹‹α12, int› -> ‹α12, int›› -> ‹α5›› -> ‹{l: ‹α5›, r: ‹int›}››", "(('a ∧ int -> 'a ∨ int) -> 'b) -> {l: 'b, r: int}", nil},
+			// value restriction: test 不是值, 没有泛化
+			expected{"(α1 -> α8)", "α1 <: (α2 -> α3), α2 :> (α5 -> α5) <: (int -> α4), α4 :> int, α5 :> int <: α4 & int, α8 :> {l: α3, r: α4}", "‹‹α1, ‹α2, ‹α4, α5, int› -> ‹α5, int›› -> ‹α3›› -> ‹α8, {l: ‹α3›, r: ‹α4, int›}››", "‹‹‹‹α5, int› -> ‹int›› -> ‹α3›› -> ‹{l: ‹α3›, r: ‹α5, int›}››", "(('a ∧ int -> int) -> 'b) -> {l: 'b, r: 'a ∨ int}", nil},
 		},
 		{
 			"fun a -> (fun k -> let test = k (fun x -> let tmp = add x 1 in x) in test) (fun f -> f a)",
 			terms.Lam("a", terms.App(terms.Lam("k", terms.Let("test", terms.App(terms.Var("k"), terms.Lam("x", terms.Let("tmp", terms.App(terms.App(terms.Var("add"), terms.Var("x")), terms.Int(1)), terms.Var("x"), false))), terms.Var("test"), false)), terms.Lam("f", terms.App(terms.Var("f"), terms.Var("a"))))),
-			expected{"(α1 -> α13)", "α1 <: α7, α7 <: α12 & int, α9 <: α13, α12 <: α9", "‹‹α1, α9, α13, α12, α7, int› -> ‹α13››", "‹‹α13, int› -> ‹α13››", "'a ∧ int -> 'a", nil},
+			expected{"(α1 -> α9)", "α1 <: α3, α3 <: α8 & int, α6 <: α9, α8 <: α6", "‹‹α1, α3, α6, α8, α9, int› -> ‹α9››", "‹‹α9, int› -> ‹α9››", "'a ∧ int -> 'a", nil},
 		},
 		{
 			"(fun k -> let test = k (fun x -> let tmp = (fun y -> add y 1) x in x) in test)",
 			terms.Lam("k", terms.Let("test", terms.App(terms.Var("k"), terms.Lam("x", terms.Let("tmp", terms.App(terms.Lam("y", terms.App(terms.App(terms.Var("add"), terms.Var("y")), terms.Int(1))), terms.Var("x")), terms.Var("x"), false))), terms.Var("test"), false)),
-			expected{"(α1 -> α11)", "α1 <: ((α8 -> α9) -> α10), α8 <: int, α9 :> α8, α11 :> α10", "‹‹α1, ‹‹α8, int› -> ‹α9, α8›› -> ‹α10›› -> ‹α11, α10››", "‹‹‹‹α8, int› -> ‹α8›› -> ‹α10›› -> ‹α10››", "(('a ∧ int -> 'a) -> 'b) -> 'b", nil},
+			expected{"(α1 -> α7)", "α1 <: ((α2 -> α2) -> α7), α2 <: α3, α3 <: int", "‹‹α1, ‹‹α2, α3, int› -> ‹α2›› -> ‹α7›› -> ‹α7››", "‹‹‹‹α2, int› -> ‹α2›› -> ‹α7›› -> ‹α7››", "(('a ∧ int -> 'a) -> 'b) -> 'b", nil},
 		},
 		{
 			"(fun k -> let test = k (fun x -> let tmp = let f = fun y -> add y 1 in f x in x) in test)",
 			terms.Lam("k", terms.Let("test", terms.App(terms.Var("k"), terms.Lam("x", terms.Let("tmp", terms.Let("f", terms.Lam("y", terms.App(terms.App(terms.Var("add"), terms.Var("y")), terms.Int(1))), terms.App(terms.Var("f"), terms.Var("x")), false), terms.Var("x"), false))), terms.Var("test"), false)),
-			expected{"(α1 -> α13)", "α1 <: ((α10 -> α11) -> α12), α10 <: int, α11 :> α10, α13 :> α12", "‹‹α1, ‹‹α10, int› -> ‹α11, α10›› -> ‹α12›› -> ‹α13, α12››", "‹‹‹‹α10, int› -> ‹α10›› -> ‹α12›› -> ‹α12››", "(('a ∧ int -> 'a) -> 'b) -> 'b", nil},
+			expected{"(α1 -> α9)", "α1 <: ((α2 -> α2) -> α9), α2 <: α6, α6 <: int", "‹‹α1, ‹‹α2, α6, int› -> ‹α2›› -> ‹α9›› -> ‹α9››", "‹‹‹‹α2, int› -> ‹α2›› -> ‹α9›› -> ‹α9››", "(('a ∧ int -> 'a) -> 'b) -> 'b", nil},
 		},
 		{
 			"fun f -> let r = fun x -> fun g -> { a = f x; b = g x } in r",
@@ -366,6 +423,11 @@ func TestRecursion(t *testing.T) {
 			terms.Let("recursive_monster", terms.Lam("x", terms.Rcd([]terms.Field{{"thing", terms.Var("x")}, {"self", terms.App(terms.Var("recursive_monster"), terms.Var("x"))}})), terms.Var("recursive_monster"), true),
 			expected{"α4", "α4 :> (α5 -> {thing: α5, self: α6}) <: (α5 -> α6), α6 :> {thing: α5, self: α6}", "‹α4, ‹α5› -> ‹{self: ‹α7›, thing: ‹α5›}››", "‹‹α5› -> ‹{self: ‹α7›, thing: ‹α5›}››", "'a -> {self: 'b, thing: 'a} as 'b", nil},
 		},
+		{
+			"let rec f = fun x -> x and f = 1 in f",
+			terms.LetGrp([]*terms.Declaration{terms.Decl("f", terms.Lam("x", terms.Var("x")), true), terms.Decl("f", terms.Int(1), true)}, terms.Var("f")),
+			expected{"", "", "", "", "", NewTypeError("duplicate definition in let rec group: f")},
+		},
 	} {
 		t.Run(tt.string, func(t *testing.T) { doTest(t, tt) })
 	}
@@ -391,7 +453,7 @@ func TestRandom(t *testing.T) {
 		{
 			"(fun x -> (let y = (x x) in 0))",
 			terms.Lam("x", terms.Let("y", terms.App(terms.Var("x"), terms.Var("x")), terms.Int(0), false)),
-			expected{"(α1 -> int)", "α1 <: (α1 -> α3)", "‹‹α1, ‹α1› -> ‹α3›› -> ‹int››", "‹‹α1, ‹α1› -> ‹›› -> ‹int››", "'a ∧ ('a -> ⊤) -> int", nil},
+			expected{"(α1 -> int)", "α1 <: (α1 -> α2)", "‹‹α1, ‹α1› -> ‹α2›› -> ‹int››", "‹‹α1, ‹α1› -> ‹›› -> ‹int››", "'a ∧ ('a -> ⊤) -> int", nil},
 		},
 		{
 			"(let rec x = (fun y -> (y (x x))) in x)",
@@ -421,12 +483,12 @@ func TestRandom(t *testing.T) {
 		{
 			"(let rec x = (let y = (x x) in (fun z -> z)) in x)",
 			terms.Let("x", terms.Let("y", terms.App(terms.Var("x"), terms.Var("x")), terms.Lam("z", terms.Var("z")), false), terms.Var("x"), true),
-			expected{"α5", "α5 :> (α6 -> α6) <: α6 & (α5 -> α7), α6 :> (α6 -> α6) <: α7, α7 :> (α6 -> α6)", "‹α5, ‹α6, α7› -> ‹α8››", "‹‹α6› -> ‹α8››", "'a -> ('a ∨ ('a -> 'b)) as 'b", nil},
+			expected{"α4", "α4 :> (α5 -> α5) <: α5 & (α4 -> α6), α5 :> (α5 -> α5) <: α6, α6 :> (α5 -> α5)", "‹α4, ‹α5, α6› -> ‹α7››", "‹‹α5› -> ‹α7››", "'a -> ('a ∨ ('a -> 'b)) as 'b", nil},
 		},
 		{
 			"(let rec x = (fun y -> (let z = (x x) in y)) in x)",
 			terms.Let("x", terms.Lam("y", terms.Let("z", terms.App(terms.Var("x"), terms.Var("x")), terms.Var("y"), false)), terms.Var("x"), true),
-			expected{"α5", "α5 :> (α6 -> α6) <: α6 & (α5 -> α7), α6 :> (α6 -> α6) <: α7, α7 :> (α6 -> α6)", "‹α5, ‹α6, α7› -> ‹α8››", "‹‹α6› -> ‹α8››", "'a -> ('a ∨ ('a -> 'b)) as 'b", nil},
+			expected{"α4", "α4 :> (α5 -> α5) <: α5 & (α4 -> α6), α5 :> (α5 -> α5) <: α6, α6 :> (α5 -> α5)", "‹α4, ‹α5, α6› -> ‹α7››", "‹‹α5› -> ‹α7››", "'a -> ('a ∨ ('a -> 'b)) as 'b", nil},
 		},
 		{
 			"(let rec x = (fun y -> {u = y; v = (x x)}) in x)",
@@ -441,17 +503,17 @@ func TestRandom(t *testing.T) {
 		{
 			"(let rec x = (fun y -> (let z = (y x) in y)) in x)",
 			terms.Let("x", terms.Lam("y", terms.Let("z", terms.App(terms.Var("y"), terms.Var("x")), terms.Var("y"), false)), terms.Var("x"), true),
-			expected{"α5", "α5 :> (α6 -> α6), α6 <: (α5 -> α7)", "‹α8›", "‹α8›", "('b ∧ ('a -> ⊤) -> 'b) as 'a", nil},
+			expected{"α4", "α4 :> (α5 -> α5), α5 <: (α4 -> α6)", "‹α7›", "‹α7›", "('b ∧ ('a -> ⊤) -> 'b) as 'a", nil},
 		},
 		{
 			"(fun x -> (let y = (x x.v) in 0))",
 			terms.Lam("x", terms.Let("y", terms.App(terms.Var("x"), terms.Sel(terms.Var("x"), "v")), terms.Int(0), false)),
-			expected{"(α1 -> int)", "α1 <: (α5 -> α6) & {v: α3}, α5 :> α3", "‹‹α1, {v: ‹α3›}, ‹α5, α3› -> ‹α6›› -> ‹int››", "‹‹{v: ‹α3›}, ‹α3› -> ‹›› -> ‹int››", "{v: 'a} ∧ ('a -> ⊤) -> int", nil},
+			expected{"(α1 -> int)", "α1 <: (α2 -> α3) & {v: α2}", "‹‹α1, {v: ‹α2›}, ‹α2› -> ‹α3›› -> ‹int››", "‹‹{v: ‹α2›}, ‹α2› -> ‹›› -> ‹int››", "{v: 'a} ∧ ('a -> ⊤) -> int", nil},
 		},
 		{
 			"let rec x = (let y = (x x) in (fun z -> z)) in (x (fun y -> y.u))",
 			terms.Let("x", terms.Let("y", terms.App(terms.Var("x"), terms.Var("x")), terms.Lam("z", terms.Var("z")), false), terms.App(terms.Var("x"), terms.Lam("y", terms.Sel(terms.Var("y"), "u"))), true),
-			expected{"α10", "α6 :> (α8 -> α9) | (α6 -> α6) <: α10 & α7, α7 :> (α8 -> α9) | (α6 -> α6), α8 <: {u: α9}, α10 :> (α6 -> α6) | (α8 -> α9)", "‹α10, ‹α6, α10, α7, α8, {u: ‹α9›}› -> ‹α11››", "‹α10, ‹α10, {u: ‹α9›}› -> ‹α11››", "'a ∨ ('a ∧ {u: 'b} -> ('b ∨ 'a ∨ ('a ∧ {u: 'b} -> 'c)) as 'c)", nil},
+			expected{"α9", "α5 :> (α7 -> α8) | (α5 -> α5) <: α9 & α6, α6 :> (α7 -> α8) | (α5 -> α5), α7 <: {u: α8}, α9 :> (α5 -> α5) | (α7 -> α8)", "‹α9, ‹α5, α6, α7, α9, {u: ‹α8›}› -> ‹α10››", "‹α9, ‹α9, {u: ‹α8›}› -> ‹α10››", "'a ∨ ('a ∧ {u: 'b} -> ('b ∨ 'a ∨ ('a ∧ {u: 'b} -> 'c)) as 'c)", nil},
 		},
 	} {
 		t.Run(tt.string, func(t *testing.T) { doTest(t, tt) })
@@ -534,9 +596,10 @@ func TestCanonicalizationProducesLCD(t *testing.T) {
 		{
 			"f",
 			Rcd([]field{
-				{"B", Int},
-				{"f", tv0},
+				{"B", Int, false},
+				{"f", tv0, false},
 			}),
+			false,
 		},
 	})
 	tv0.prependLower(st0)
@@ -546,15 +609,17 @@ func TestCanonicalizationProducesLCD(t *testing.T) {
 		{
 			"f",
 			Rcd([]field{
-				{"B", Int},
+				{"B", Int, false},
 				{
 					"f",
 					Rcd([]field{
-						{"f", tv1},
-						{"a", Int},
+						{"f", tv1, false},
+						{"a", Int, false},
 					}),
+					false,
 				},
 			}),
+			false,
 		},
 	})
 	tv1.prependLower(st1)
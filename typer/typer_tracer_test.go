@@ -0,0 +1,80 @@
+package typer
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/goghcrow/simple-sub/terms"
+)
+
+func TestIndentTracer(t *testing.T) {
+	var b strings.Builder
+	typer := NewTyper(WithTracer(NewIndentTracer(&b)))
+	// fun x -> x 42
+	term := terms.Lam("x", terms.App(terms.Var("x"), terms.Int(42)))
+	st := typer.inferType(term, NewCtx(map[string]TypeScheme{}))
+	typer.simplifyType(typer.canonicalizeType(st))
+
+	expect := `fresh α0
+fresh α1
+α0 <: (int -> α1)
+  bound α0 <: (int -> α1)
+simplify: remove α0 (occurs in one polarity only)
+`
+	if b.String() != expect {
+		t.Errorf("expect %s actual %s", expect, b.String())
+	}
+}
+
+// 试探重载时撤销的约束不出现在 trace 中
+func TestTrialTracer(t *testing.T) {
+	var b strings.Builder
+	typer := NewTyper(WithTracer(NewIndentTracer(&b)))
+	term := terms.AppN(terms.Var("add"), terms.Float(1.5), terms.Float(2.5))
+	typer.inferType(term, typer.Builtins())
+	if strings.Contains(b.String(), "float <: int") {
+		t.Errorf("unexpected trial events in %s", b.String())
+	}
+}
+
+func TestJSONTracer(t *testing.T) {
+	var b strings.Builder
+	typer := NewTyper(WithTracer(NewJSONTracer(&b)))
+	pgrm := parsePgrm(`
+	let id = fun x -> x
+	let ab = {u : id 0,  v : id true }
+	let rec f = fun x -> if true then x else f x
+	let twice = fun f x -> f (f x)
+`)
+	tyv, err := typer.inferTypes(pgrm, typer.Builtins())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, poly := range tyv {
+		typer.simplifyType(typer.canonicalizeType(poly.instantiate(typer, 0)))
+	}
+
+	kinds := map[EventKind]int{}
+	maxDepth := 0
+	sc := bufio.NewScanner(strings.NewReader(b.String()))
+	for sc.Scan() {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		kinds[e.Kind]++
+		if e.Depth > maxDepth {
+			maxDepth = e.Depth
+		}
+	}
+	for _, k := range []EventKind{EvFresh, EvConstrain, EvBound, EvInstantiate, EvMerge, EvRemove, EvSubst} {
+		if kinds[k] == 0 {
+			t.Errorf("expect %s events", k)
+		}
+	}
+	if maxDepth == 0 {
+		t.Errorf("expect nested constrain steps")
+	}
+}
@@ -0,0 +1,35 @@
+package typer
+
+import (
+	"testing"
+)
+
+func TestTuples(t *testing.T) {
+	testTypes(t, freshEnv(nil), []typeCase{
+		{"let x = (1, true)._2", "bool"},
+		// 宽度子类型, 只约束投影需要的前缀
+		{"let x = (1, true, \"s\")._1", "int"},
+		{"let f = fun t -> t._2", "(⊤, 'a) -> 'a"},
+		{"let f = fun t -> (t._1, t._2)", "('a, 'b) -> ('a, 'b)"},
+		{"let f = fun t -> t._1", "('a,) -> 'a"},
+		// 接收者不是 tuple 时是字段选择
+		{"let x = { _1: 1 }._1", "int"},
+		{"let x = let r = {_1: true, _2: 1} in r._1", "bool"},
+		{"let f = fun b -> if b then (1, true) else (2.5, 3, 4)", "bool -> (float, bool ∨ int)"},
+		{"let f = fun t -> fun g -> g t._2 t._1", "('a, 'b) -> ('b -> 'a -> 'c) -> 'c"},
+		// 解构
+		{"let swap = fun p -> let (a, b) = p in (b, a)", "('a, 'b) -> ('b, 'a)"},
+		{"let f = fun r -> let {x, y} = r in (y, x)", "{x: 'a, y: 'b} -> ('b, 'a)"},
+		{"let x = let (f, n) = (fun x -> x, 1) in (f n, f true)", "(int, bool)"},
+		{"let x = let {a, b} = {a: 1, b: 2.5, c: true} in a + b", "float"},
+	})
+
+	testErrors(t, freshEnv(nil), []typeCase{
+		{"let x = (1, 2)._3", "missing element: _3 in (int, int)"},
+		{"let x = let (a, b, c) = (1, 2) in a", "missing element: _3 in (int, int)"},
+		{"let x = let {a, b} = {a: 1} in a", "missing field: b in {a: int}"},
+		{"let x = 1._1", "cannot constrain int <: ('a,)"},
+		// 类型未知的接收者按 tuple 推导
+		{"let f = fun r -> r._1\nlet x = f {_1: true}", "cannot constrain {_1: bool} <: ('a,)"},
+	})
+}
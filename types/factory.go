@@ -1,5 +1,7 @@
 package types
 
+import "sort"
+
 func newTop() *TopType {
 	t := &TopType{}
 	t.typeImpl = &typeImpl{Type: t}
@@ -35,6 +37,72 @@ func Record(fields []Field) *RecordType {
 	t.typeImpl = &typeImpl{Type: t}
 	return t
 }
+
+// Extension 基类型 base 删除 removed 字段并扩展 fields 字段, base 是记录时折叠为记录
+func Extension(base Type, fields []Field, removed []string) Type {
+	rcd, ok := base.(*RecordType)
+	if !ok {
+		t := &ExtensionType{Base: base, Fields: fields, Removed: removed}
+		t.typeImpl = &typeImpl{Type: t}
+		return t
+	}
+	m := make(map[string]Field, len(rcd.Fields)+len(fields))
+	for _, fd := range rcd.Fields {
+		m[fd.Name] = fd
+	}
+	for _, name := range removed {
+		delete(m, name)
+	}
+	for _, fd := range fields {
+		m[fd.Name] = fd
+	}
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	xs := make([]Field, len(names))
+	for i, name := range names {
+		xs[i] = m[name]
+	}
+	return Record(xs)
+}
+func Variant(tags []Field) *VariantType {
+	t := &VariantType{Tags: tags}
+	t.typeImpl = &typeImpl{Type: t}
+	return t
+}
+func Ref(in Type, out Type) *RefType {
+	t := &RefType{In: in, Out: out}
+	t.typeImpl = &typeImpl{Type: t}
+	return t
+}
+
+// Neg 否定类型, 按 De Morgan 律把否定推到并集、交集的成员上 (否定范式), 不展开为析取范式
+// ~~T = T, ~(A ∨ B) = ~A ∧ ~B, ~(A ∧ B) = ~A ∨ ~B, ~⊤ = ⊥, ~⊥ = ⊤
+func Neg(ty Type) Type {
+	switch ty := ty.(type) {
+	case *TopType:
+		return Bot
+	case *BotType:
+		return Top
+	case *NegationType:
+		return ty.Neg
+	case *UnionType:
+		return Inter(Neg(ty.Lhs), Neg(ty.Rhs))
+	case *InterType:
+		return Union(Neg(ty.Lhs), Neg(ty.Rhs))
+	default:
+		t := &NegationType{Neg: ty}
+		t.typeImpl = &typeImpl{Type: t}
+		return t
+	}
+}
+func Ctor(name string, args []CtorArg) *CtorType {
+	t := &CtorType{Name: name, Args: args}
+	t.typeImpl = &typeImpl{Type: t}
+	return t
+}
 func Recur(UV *TypeVariable, body Type) *RecursiveType {
 	t := &RecursiveType{UV: UV, Body: body}
 	t.typeImpl = &typeImpl{Type: t}
@@ -3,6 +3,7 @@ package types
 import (
 	"fmt"
 	"github.com/goghcrow/simple-sub/util"
+	"strings"
 )
 
 func (t *typeImpl) typeVarsList() []*TypeVariable {
@@ -47,6 +48,27 @@ func (t *typeImpl) Show() string {
 	return t.showIn(ctx, 0)
 }
 
+// Equal 结构相等, 类型变量按身份比较
+func Equal(a, b Type) bool {
+	ctx := Ctx{}
+	for _, ty := range []Type{a, b} {
+		for _, vr := range ty.impl().typeVarsList() {
+			ctx.Put(vr, fmt.Sprintf("'%d", vr.hash))
+		}
+	}
+	return a.impl().showIn(ctx, 0) == b.impl().showIn(ctx, 0)
+}
+
+// Occurs 类型变量 tv 是否出现在 ty 中
+func Occurs(tv *TypeVariable, ty Type) bool {
+	for _, vr := range ty.impl().typeVarsList() {
+		if vr.hash == tv.hash {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *typeImpl) parensIf(str string, cnd bool) string {
 	if cnd {
 		return "(" + str + ")"
@@ -76,13 +98,52 @@ func (t *typeImpl) showIn(ctx Ctx, outerPrec int) string {
 		for i, el := range ty.Elms {
 			xs[i] = el.impl().showIn(ctx, 0)
 		}
+		// 单元素的 tuple 与括号区分, e.g. (int,)
+		if len(xs) == 1 {
+			return "(" + xs[0] + ",)"
+		}
 		return util.JoinStr(xs, ", ", "(", ")")
 	case *RecordType:
 		xs := make([]string, len(ty.Fields))
 		for i, fd := range ty.Fields {
-			xs[i] = fmt.Sprintf("%s: %s", fd.Name, fd.Type.impl().showIn(ctx, 0))
+			name := fd.Name
+			if fd.Optional {
+				name += "?"
+			}
+			xs[i] = fmt.Sprintf("%s: %s", name, fd.Type.impl().showIn(ctx, 0))
 		}
 		return util.JoinStr(xs, ", ", "{", "}")
+	case *ExtensionType:
+		base := ty.Base.impl().showIn(ctx, 30)
+		for _, name := range ty.Removed {
+			base += " \\ " + name
+		}
+		if len(ty.Fields) == 0 {
+			return t.parensIf(base, outerPrec > 30)
+		}
+		xs := make([]string, len(ty.Fields))
+		for i, fd := range ty.Fields {
+			xs[i] = fmt.Sprintf("%s: %s", fd.Name, fd.Type.impl().showIn(ctx, 0))
+		}
+		return fmt.Sprintf("{%s with %s}", base, strings.Join(xs, ", "))
+	case *VariantType:
+		xs := make([]string, len(ty.Tags))
+		for i, tag := range ty.Tags {
+			// 无参数的标签 payload 为空记录, 不绑定参数的分支 payload 为 ⊤
+			if rcd, ok := tag.Type.(*RecordType); (ok && len(rcd.Fields) == 0) || tag.Type == Top {
+				xs[i] = tag.Name
+			} else {
+				xs[i] = fmt.Sprintf("%s %s", tag.Name, tag.Type.impl().showIn(ctx, 30))
+			}
+		}
+		return util.JoinStr(xs, " | ", "[", "]")
+	case *RefType:
+		in := ty.In.impl().showIn(ctx, 0)
+		out := ty.Out.impl().showIn(ctx, 0)
+		if in == out {
+			return fmt.Sprintf("Ref[%s]", in)
+		}
+		return fmt.Sprintf("Ref[in %s, out %s]", in, out)
 	case *UnionType:
 		lhs := ty.Lhs.impl().showIn(ctx, 20)
 		rhs := ty.Rhs.impl().showIn(ctx, 20)
@@ -91,6 +152,33 @@ func (t *typeImpl) showIn(ctx Ctx, outerPrec int) string {
 		lhs := ty.Lhs.impl().showIn(ctx, 25)
 		rhs := ty.Rhs.impl().showIn(ctx, 25)
 		return t.parensIf(fmt.Sprintf("%s ∧ %s", lhs, rhs), outerPrec > 25)
+	case *NegationType:
+		return "~" + ty.Neg.impl().showIn(ctx, 40)
+	case *CtorType:
+		// 不变参数的两个分量相同时只显示一个, e.g. Cell[int], 否则 Cell[in int, out int ∨ bool]
+		if len(ty.Args) == 0 {
+			return ty.Name
+		}
+		sep := ", "
+		xs := make([]string, len(ty.Args))
+		for i, arg := range ty.Args {
+			switch {
+			case arg.In == nil:
+				xs[i] = arg.Out.impl().showIn(ctx, 0)
+			case arg.Out == nil:
+				xs[i] = arg.In.impl().showIn(ctx, 0)
+			default:
+				in := arg.In.impl().showIn(ctx, 0)
+				out := arg.Out.impl().showIn(ctx, 0)
+				if in == out {
+					xs[i] = in
+				} else {
+					xs[i] = fmt.Sprintf("in %s, out %s", in, out)
+					sep = "; "
+				}
+			}
+		}
+		return fmt.Sprintf("%s[%s]", ty.Name, strings.Join(xs, sep))
 	default:
 		panic("unreached")
 	}
@@ -120,10 +208,38 @@ func (t *typeImpl) children() []Type {
 			xs[i] = fd.Type
 		}
 		return xs
+	case *ExtensionType:
+		xs := make([]Type, 1+len(ty.Fields))
+		xs[0] = ty.Base
+		for i, fd := range ty.Fields {
+			xs[i+1] = fd.Type
+		}
+		return xs
+	case *VariantType:
+		xs := make([]Type, len(ty.Tags))
+		for i, tag := range ty.Tags {
+			xs[i] = tag.Type
+		}
+		return xs
+	case *RefType:
+		return []Type{ty.In, ty.Out}
 	case *UnionType:
 		return []Type{ty.Lhs, ty.Rhs}
 	case *InterType:
 		return []Type{ty.Lhs, ty.Rhs}
+	case *NegationType:
+		return []Type{ty.Neg}
+	case *CtorType:
+		var xs []Type
+		for _, arg := range ty.Args {
+			if arg.In != nil {
+				xs = append(xs, arg.In)
+			}
+			if arg.Out != nil {
+				xs = append(xs, arg.Out)
+			}
+		}
+		return xs
 	case *RecursiveType:
 		return []Type{ty.Type}
 	default:
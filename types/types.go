@@ -41,14 +41,52 @@ type (
 		*typeImpl
 		Elms []Type
 	}
+	// Field 记录的字段或标签联合的标签, 只有记录的字段可以是可选的, e.g. {port?: int}
 	Field struct {
-		Name string
-		Type Type
+		Name     string
+		Type     Type
+		Optional bool
 	}
 	RecordType struct {
 		*typeImpl
 		Fields []Field
 	}
+	// ExtensionType 记录扩展与删除, e.g. {'a \ y with x: int}
+	// 基类型是记录时直接折叠为 RecordType, 参见 Extension
+	ExtensionType struct {
+		*typeImpl
+		Base    Type
+		Fields  []Field
+		Removed []string
+	}
+	// VariantType 带标签的开放联合类型, e.g. [Some int | None]
+	VariantType struct {
+		*typeImpl
+		Tags []Field
+	}
+	// RefType 引用类型, 写入类型 In 逆变, 读取类型 Out 协变
+	RefType struct {
+		*typeImpl
+		In  Type
+		Out Type
+	}
+	// CtorType 用户声明的类型构造器, e.g. Box[int], Cell[in int, out bool]
+	CtorType struct {
+		*typeImpl
+		Name string
+		Args []CtorArg
+	}
+	// CtorArg 构造器参数的逆变分量 In 与协变分量 Out, 按参数的型变只有一个分量时另一个为 nil
+	CtorArg struct {
+		In  Type
+		Out Type
+	}
+	// NegationType 否定类型 ~T, 除 T 之外的所有值, e.g. int ∧ ~0
+	// 由 Neg 构造, 否定只出现在原子类型上, 参见 Neg
+	NegationType struct {
+		*typeImpl
+		Neg Type
+	}
 	RecursiveType struct {
 		*typeImpl
 		UV   *TypeVariable
@@ -77,6 +115,11 @@ func (i *InterType) impl() *typeImpl     { return i.typeImpl }
 func (f *FunctionType) impl() *typeImpl  { return f.typeImpl }
 func (r *TupleType) impl() *typeImpl     { return r.typeImpl }
 func (r *RecordType) impl() *typeImpl    { return r.typeImpl }
+func (v *VariantType) impl() *typeImpl   { return v.typeImpl }
+func (e *ExtensionType) impl() *typeImpl { return e.typeImpl }
+func (r *RefType) impl() *typeImpl       { return r.typeImpl }
+func (n *NegationType) impl() *typeImpl  { return n.typeImpl }
+func (c *CtorType) impl() *typeImpl      { return c.typeImpl }
 func (r *RecursiveType) impl() *typeImpl { return r.typeImpl }
 func (p *PrimitiveType) impl() *typeImpl { return p.typeImpl }
 func (t *TypeVariable) impl() *typeImpl  { return t.typeImpl }